@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hangulize/hre"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,6 +26,15 @@ func mustParseSpec(hgl string) *Spec {
 	return spec
 }
 
+func newRule(id int, from, to string) Rule {
+	p, err := hre.NewPattern(from, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	rp := hre.NewRPattern(to, nil, nil)
+	return Rule{id, p, rp}
+}
+
 func assertHangulize(t *testing.T, spec *Spec, expected string, word string) {
 	h := NewHangulizer(spec)
 
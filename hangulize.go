@@ -1,5 +1,7 @@
 package hangulize
 
+import "sort"
+
 // Hangulize transcribes a non-Korean word into Hangul, which is the Korean
 // alphabet.
 //
@@ -23,11 +25,23 @@ func Hangulize(lang string, word string) string {
 type Hangulizer struct {
 	spec        *Spec
 	phonemizers map[string]Phonemizer
+	fallback    *Spec
+	inputNorm   *NormalizeConfig
+
+	// The placeholder for untranscribed letters.
+	placeholder string
+	collapse    bool
+
+	// The maximum ratio of the output runes to the input runes.
+	maxExpansion float64
 }
 
 // NewHangulizer creates a Hangulizer for a spec.
 func NewHangulizer(spec *Spec) *Hangulizer {
-	return &Hangulizer{spec, make(map[string]Phonemizer)}
+	return &Hangulizer{
+		spec:        spec,
+		phonemizers: make(map[string]Phonemizer),
+	}
 }
 
 // Spec returns the underlying spec.
@@ -35,6 +49,49 @@ func (h *Hangulizer) Spec() *Spec {
 	return h.spec
 }
 
+// SetFallback sets a fallback spec. The transcribe rules in the fallback spec
+// are applied to the letters which the primary transcribe rules have left
+// untranscribed. The letters in the script of the fallback spec are also
+// transcribed even if the primary spec uses another script. Pass nil to unset
+// it.
+func (h *Hangulizer) SetFallback(fallback *Spec) {
+	h.fallback = fallback
+}
+
+// Fallback returns the fallback spec.
+func (h *Hangulizer) Fallback() *Spec {
+	return h.fallback
+}
+
+// SetInputNormalizer sets the input normalization which runs before any rule
+// matches. Pass nil to unset it.
+func (h *Hangulizer) SetInputNormalizer(config *NormalizeConfig) {
+	h.inputNorm = config
+}
+
+// SetPlaceholder sets the placeholder for untranscribed letters. By default,
+// untranscribed letters are dropped. If collapse is true, consecutive
+// untranscribed letters are replaced with a single placeholder. Pass an empty
+// placeholder to drop them again.
+func (h *Hangulizer) SetPlaceholder(placeholder string, collapse bool) {
+	h.placeholder = placeholder
+	h.collapse = collapse
+}
+
+// SetMaxExpansion limits how many times a word may expand in runes while
+// transcribing. It guards against rules which explode the word on adversarial
+// input. A word which exceeds the limit is not transcribed. Pass 0 to unset it.
+//
+// The input is measured in runes. The rewritten and transcribed word is
+// measured in runes of the intermediate Jamo notation, such as "ㅎㅏ-ㄴ",
+// after the Rewrite and Transcribe steps. The tail markers ("-") and the
+// letters which just pass through the pipeline are not counted. So "han"
+// transcribed as "ㅎㅏ-ㄴ" expands 1 time.
+//
+func (h *Hangulizer) SetMaxExpansion(ratio float64) {
+	h.maxExpansion = ratio
+}
+
 // UsePhonemizer keeps a phonemizer for ready to use.
 func (h *Hangulizer) UsePhonemizer(p Phonemizer) bool {
 	return usePhonemizer(p, h.phonemizers)
@@ -51,9 +108,20 @@ func (h *Hangulizer) GetPhonemizer(id string) (Phonemizer, bool) {
 	return p, ok
 }
 
-// Hangulize transcribes a loanword into Hangul.
+// Hangulize transcribes a loanword into Hangul. If the word expands over the
+// max expansion ratio, it returns the word as is.
 func (h *Hangulizer) Hangulize(word string) string {
-	p := pipeline{h, nil}
+	transcribed, err := h.HangulizeChecked(word)
+	if err != nil {
+		return word
+	}
+	return transcribed
+}
+
+// HangulizeChecked transcribes a loanword into Hangul. It fails if the word
+// expands over the max expansion ratio.
+func (h *Hangulizer) HangulizeChecked(word string) (string, error) {
+	p := pipeline{h: h}
 	return p.forward(word)
 }
 
@@ -61,9 +129,95 @@ func (h *Hangulizer) Hangulize(word string) string {
 // and returns the traced internal events too.
 func (h *Hangulizer) HangulizeTrace(word string) (string, Traces) {
 	var tr tracer
-	p := pipeline{h, &tr}
+	p := pipeline{h: h, tr: &tr}
+
+	transcribed, err := p.forward(word)
+	if err != nil {
+		return word, tr.Traces()
+	}
+
+	return transcribed, tr.Traces()
+}
+
+// HangulizeResidue transcribes a loanword into Hangul and returns the letters
+// which no transcribe rule has transcribed too. The letters are from the
+// normalized word in order. A letter which has been rewritten counts as
+// transcribed if any of its rewritten letters has been transcribed. A letter
+// which a rule has removed is not residue.
+//
+// The letters out of the script of the spec are residue as well. Spaces,
+// punctuations, digits, and Hangul are not residue.
+//
+func (h *Hangulizer) HangulizeResidue(word string) (string, []rune) {
+	var cov coverage
+	p := pipeline{h: h, cov: &cov}
+
+	transcribed, err := p.forward(word)
+	if err != nil {
+		return word, cov.residue
+	}
 
-	word = p.forward(word)
+	return transcribed, cov.residue
+}
+
+// CoverageReport reports how many letters have been transcribed. The
+// percentages are between 0 and 100.
+type CoverageReport struct {
+	Words     map[string]float64 // The coverage per word.
+	Total     float64            // The aggregate coverage.
+	Uncovered []rune             // The never transcribed letters.
+	Failed    []string           // The words failed to transcribe.
+}
+
+// Coverage transcribes the words in the corpus and reports the percentage of
+// letters in the normalized words which have been transcribed by the
+// transcribe rules, as HangulizeResidue does. So rewriting doesn't change the
+// number of letters. The letters out of the script of the spec are never
+// covered. A word without any letter to transcribe is fully covered.
+//
+// A word which expands over the max expansion ratio is reported as failed. It
+// is not counted in the coverage.
+//
+func (h *Hangulizer) Coverage(corpus []string) CoverageReport {
+	report := CoverageReport{Words: make(map[string]float64)}
+	var letters, residue int
+	uncovered := make(map[rune]bool)
 
-	return word, tr.Traces()
+	for _, word := range corpus {
+		var cov coverage
+		p := pipeline{h: h, cov: &cov}
+
+		if _, err := p.forward(word); err != nil {
+			report.Failed = append(report.Failed, word)
+			continue
+		}
+
+		report.Words[word] = percentage(cov.letters-len(cov.residue), cov.letters)
+
+		letters += cov.letters
+		residue += len(cov.residue)
+
+		for _, ch := range cov.residue {
+			if !uncovered[ch] {
+				uncovered[ch] = true
+				report.Uncovered = append(report.Uncovered, ch)
+			}
+		}
+	}
+
+	report.Total = percentage(letters-residue, letters)
+
+	sort.Slice(report.Uncovered, func(i, j int) bool {
+		return report.Uncovered[i] < report.Uncovered[j]
+	})
+
+	return report
+}
+
+// percentage calculates n/total in percent. It is 100 if total is 0.
+func percentage(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) / float64(total) * 100
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/unicode/norm"
 )
 
 // TestLang generates subtests for bundled lang specs.
@@ -69,6 +70,16 @@ func TestSpecials(t *testing.T) {
 	assert.Equal(t, "<글로리아>", Hangulize("ita", "<gloria>"))
 }
 
+func TestPunctuationPreserved(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"hello" -> "ㅎㅔ-ㄹㄹㅗ"
+		"world" -> "ㅇㅝ-ㄹㄷ"
+	`)
+	assert.Equal(t, "헬로, 월드!", hangulize(spec, "hello, world!"))
+	assert.Equal(t, "(헬로) 1 월드...", hangulize(spec, "(hello) 1 world..."))
+}
+
 func TestHyphen(t *testing.T) {
 	spec := mustParseSpec(`
 	transcribe:
@@ -174,6 +185,252 @@ func TestVarToVar(t *testing.T) {
 	assert.Equal(t, "ei", hangulize(spec, "bc"))
 }
 
+func TestCombiningSequence(t *testing.T) {
+	spec := mustParseSpec(`
+	normalize:
+		"é" = "É"
+
+	vars:
+		"acute" = "é"
+
+	transcribe:
+		"<acute>" -> "ㅔ"
+		"e"       -> "ㅣ"
+	`)
+	h := NewHangulizer(spec)
+
+	// The Unicode form is kept by default. U+0301 just passes through.
+	assert.Equal(t, "에", h.Hangulize("é"))
+	assert.Equal(t, "이\u0301", h.Hangulize("e\u0301"))
+
+	// NFC composes the decomposed input.
+	h.SetInputNormalizer(&NormalizeConfig{Unicode: true, Form: norm.NFC})
+	assert.Equal(t, "에", h.Hangulize("é"))
+	assert.Equal(t, "에", h.Hangulize("e\u0301"))
+	assert.Equal(t, "이", h.Hangulize("e"))
+
+	// NFD is not composed again.
+	h.SetInputNormalizer(&NormalizeConfig{Unicode: true, Form: norm.NFD})
+	assert.Equal(t, "이\u0301", h.Hangulize("é"))
+	assert.Equal(t, "이\u0301", h.Hangulize("e\u0301"))
+}
+
+func TestFallback(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+	`)
+	fallback := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅏ"
+		"b" -> "ㅂ"
+	`)
+
+	h := NewHangulizer(spec)
+	assert.Equal(t, "으", h.Hangulize("ab"))
+
+	// The fallback handles only the untranscribed "b".
+	h.SetFallback(fallback)
+	assert.Equal(t, fallback, h.Fallback())
+	assert.Equal(t, "으브", h.Hangulize("ab"))
+
+	h.SetFallback(nil)
+	assert.Equal(t, "으", h.Hangulize("ab"))
+}
+
+func TestFallbackScript(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+	`)
+	fallback := mustParseSpec(`
+	lang:
+		id     = "rus"
+		codes  = "ru", "rus"
+		script = "cyrillic"
+
+	transcribe:
+		"ж" -> "ㅈ"
+	`)
+
+	// "ж" is out of the Latin script. It just passes through.
+	h := NewHangulizer(spec)
+	assert.Equal(t, "으ж", h.Hangulize("aж"))
+
+	// The fallback knows the Cyrillic script.
+	h.SetFallback(fallback)
+	assert.Equal(t, "으즈", h.Hangulize("aж"))
+}
+
+func TestHangulizeResidue(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+		"c" -> "ㅊ"
+	`)
+	h := NewHangulizer(spec)
+
+	transcribed, residue := h.HangulizeResidue("abc")
+	assert.Equal(t, "으츠", transcribed)
+	assert.Equal(t, []rune{'b'}, residue)
+
+	transcribed, residue = h.HangulizeResidue("ac ca")
+	assert.Equal(t, "으츠 츠으", transcribed)
+	assert.Len(t, residue, 0)
+
+	// "ж" is out of the Latin script.
+	transcribed, residue = h.HangulizeResidue("aж, 1가")
+	assert.Equal(t, "으ж, 1가", transcribed)
+	assert.Equal(t, []rune{'ж'}, residue)
+}
+
+func TestHangulizeResidueRewrite(t *testing.T) {
+	spec := mustParseSpec(`
+	rewrite:
+		"c"  -> "k"
+		"h"  -> ""
+		"x'" -> "x"
+		"q"  -> "ab"
+
+	transcribe:
+		"a" -> "ㅇ"
+	`)
+	h := NewHangulizer(spec)
+
+	// "c" is rewritten to "k" but never transcribed. "h" is removed. "'" is
+	// a punctuation.
+	_, residue := h.HangulizeResidue("ca'h")
+	assert.Equal(t, []rune{'c'}, residue)
+
+	// "q" is rewritten to "ab" and "a" is transcribed.
+	_, residue = h.HangulizeResidue("qb")
+	assert.Equal(t, []rune{'b'}, residue)
+}
+
+func TestCoverage(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+		"c" -> "ㅊ"
+	`)
+	h := NewHangulizer(spec)
+
+	report := h.Coverage([]string{"ac", "abcd", "b", "!", "aж"})
+
+	assert.Equal(t, map[string]float64{
+		"ac":   100,
+		"abcd": 50,
+		"b":    0,
+		"!":    100,
+		"aж":   50,
+	}, report.Words)
+	assert.InDelta(t, 100.0*5/9, report.Total, 1e-9)
+	assert.Equal(t, []rune{'b', 'd', 'ж'}, report.Uncovered)
+	assert.Len(t, report.Failed, 0)
+}
+
+func TestCoverageRewrite(t *testing.T) {
+	spec := mustParseSpec(`
+	rewrite:
+		"a" -> "aaaa"
+		"c" -> "k"
+
+	transcribe:
+		"a" -> "ㅇ"
+	`)
+	h := NewHangulizer(spec)
+
+	// The letters are counted in the input, not after rewriting.
+	report := h.Coverage([]string{"ab", "ca"})
+	assert.Equal(t, map[string]float64{"ab": 50, "ca": 50}, report.Words)
+	assert.Equal(t, []rune{'b', 'c'}, report.Uncovered)
+
+	// "ab" expands 4 times.
+	h.SetMaxExpansion(2)
+
+	report = h.Coverage([]string{"ab", "b"})
+	assert.Equal(t, map[string]float64{"b": 0}, report.Words)
+	assert.Equal(t, 0.0, report.Total)
+	assert.Equal(t, []string{"ab"}, report.Failed)
+}
+
+func TestPlaceholder(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+		"c" -> "ㅊ"
+	`)
+	h := NewHangulizer(spec)
+
+	assert.Equal(t, "으츠", h.Hangulize("abbc"))
+
+	h.SetPlaceholder("?", false)
+	assert.Equal(t, "으??츠", h.Hangulize("abbc"))
+	assert.Equal(t, "??", h.Hangulize("bb"))
+
+	h.SetPlaceholder("[?]", true)
+	assert.Equal(t, "으[?]츠", h.Hangulize("abbc"))
+	assert.Equal(t, "[?] [?]", h.Hangulize("bb bb"))
+
+	h.SetPlaceholder("", false)
+	assert.Equal(t, "으츠", h.Hangulize("abbc"))
+}
+
+func TestMaxExpansion(t *testing.T) {
+	spec := mustParseSpec(`
+	rewrite:
+		"a" -> "aaaa"
+
+	transcribe:
+		"a" -> "ㅇ"
+		"b" -> "ㅂㅡㅂ"
+	`)
+	h := NewHangulizer(spec)
+
+	assert.Equal(t, "으으으으", h.Hangulize("a"))
+
+	// Rewrite expands "a" 4 times.
+	h.SetMaxExpansion(3)
+
+	_, err := h.HangulizeChecked("a")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Rewrite")
+	}
+	assert.Equal(t, "a", h.Hangulize("a"))
+
+	// Transcribe expands "b" 3 times.
+	h.SetMaxExpansion(2)
+
+	_, err = h.HangulizeChecked("b")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Transcribe")
+	}
+
+	h.SetMaxExpansion(4)
+
+	word, err := h.HangulizeChecked("ab")
+	assert.NoError(t, err)
+	assert.Equal(t, "으으으으브브", word)
+
+	h.SetMaxExpansion(0)
+	assert.Equal(t, "으으으으", h.Hangulize("a"))
+}
+
+func TestMaxExpansionJamo(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"han" -> "ㅎㅏ-ㄴ"
+	`)
+	h := NewHangulizer(spec)
+
+	// Neither the tail markers nor the passing through "," are counted.
+	h.SetMaxExpansion(1)
+
+	word, err := h.HangulizeChecked("han, han")
+	assert.NoError(t, err)
+	assert.Equal(t, "한, 한", word)
+}
+
 func TestUnknownLang(t *testing.T) {
 	assert.Equal(t, "hello", Hangulize("unknown", "hello"))
 }
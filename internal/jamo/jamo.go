@@ -5,6 +5,11 @@ to composed Hangul syllables.
 	fmt.Println(jamo.ComposeHangul("ㅈㅏㅁㅗ"))
 	// Output: 자모
 
+It also decomposes Hangul syllables into Jamo phonemes in the same format.
+
+	fmt.Println(jamo.DecomposeHangul("한글"))
+	// Output: ㅎㅏ-ㄴㄱㅡ-ㄹ
+
 */
 package jamo
 
@@ -28,6 +33,37 @@ func ComposeHangul(word string) string {
 	return c.Compose()
 }
 
+// DecomposeHangul converts composed Hangul syllables to decomposed Jamo
+// phonemes. It is the inverse of ComposeHangul. Tail Jaeums are prefixed with
+// a hyphen:
+//
+//   "한글" -> "ㅎㅏ-ㄴㄱㅡ-ㄹ"
+//
+func DecomposeHangul(word string) string {
+	var buf bytes.Buffer
+
+	for _, ch := range word {
+		_, _, _, isComposed := analyzeHangul(ch)
+
+		if !isComposed {
+			buf.WriteRune(ch)
+			continue
+		}
+
+		l, m, t := hangul.Split(ch)
+
+		buf.WriteRune(l)
+		buf.WriteRune(m)
+
+		if t != 0 {
+			buf.WriteRune('-')
+			buf.WriteRune(t)
+		}
+	}
+
+	return buf.String()
+}
+
 const (
 	lead   = 0
 	medial = 1
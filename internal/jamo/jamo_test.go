@@ -22,6 +22,19 @@ func TestComposeHangulNonHangul(t *testing.T) {
 	assert.Equal(t, "안녕, world", ComposeHangul("ㅇㅏ-ㄴㄴㅕ-ㅇ, world"))
 }
 
+func TestDecomposeHangul(t *testing.T) {
+	assert.Equal(t, "ㅎㅏ-ㄴㄱㅡ-ㄹ", DecomposeHangul("한글"))
+	assert.Equal(t, "ㄲㅣ-ㅇㄲㅏ-ㅇ", DecomposeHangul("낑깡"))
+	assert.Equal(t, "ㅇㅏ-ㄴㄴㅕ-ㅇ, world", DecomposeHangul("안녕, world"))
+	assert.Equal(t, "ㅎㅏㄴ", DecomposeHangul("ㅎㅏㄴ"))
+}
+
+func TestDecomposeComposeRoundTrip(t *testing.T) {
+	for _, word := range []string{"한글", "낑깡", "값", "안녕, world"} {
+		assert.Equal(t, word, ComposeHangul(DecomposeHangul(word)))
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Benchmarks
 
@@ -31,6 +44,12 @@ func BenchmarkComposeHangul(b *testing.B) {
 	}
 }
 
+func BenchmarkDecomposeHangul(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		DecomposeHangul("한글")
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Examples
 
@@ -43,3 +62,8 @@ func ExampleComposeHangul_interpolation() {
 	fmt.Println(ComposeHangul("ㅗㅈ"))
 	// Output: 오즈
 }
+
+func ExampleDecomposeHangul() {
+	fmt.Println(DecomposeHangul("한글라이즈"))
+	// Output: ㅎㅏ-ㄴㄱㅡ-ㄹㄹㅏㅇㅣㅈㅡ
+}
@@ -0,0 +1,180 @@
+package hangulize
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/hangulize/hangulize/internal/subword"
+)
+
+var reVarRef = regexp.MustCompile(`<([^<>]+)>`)
+
+// ValidateSpec compiles the pattern expressions and collects all errors at
+// once, including the references to undefined vars. Macros are expanded
+// before looking for var references. An undefined macro is just a literal so
+// it cannot be detected.
+//
+// It reports the same errors as ParseSpec would for the macros, the vars, and
+// each Pattern, but it discards the compiled Patterns immediately. So it is
+// cheap enough to lint a large spec.
+//
+// It also warns about a Pattern which consists of only edges, such as "^^$$".
+// ParseSpec accepts it but it can match only empty input.
+func ValidateSpec(
+	exprs []string,
+
+	macros map[string]string,
+	vars map[string][]string,
+
+) []error {
+
+	var errs []error
+
+	if err := checkMacros(macros); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := checkVars(vars, defaultMaxVarValueLength); err != nil {
+		errs = append(errs, err)
+	}
+
+	expander := newMacroExpander(macros)
+
+	for _, expr := range exprs {
+		expanded := expander.Replace(expr)
+		undefined := false
+
+		for _, m := range reVarRef.FindAllStringSubmatch(expanded, -1) {
+			if _, ok := vars[m[1]]; !ok {
+				err := errors.Errorf("%#v refers undefined var: %s", expr, m[1])
+				errs = append(errs, err)
+				undefined = true
+			}
+		}
+
+		if undefined {
+			// It will not compile anyway.
+			continue
+		}
+
+		_, err := newPattern(expr, macros, vars)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to compile %#v", expr))
+			continue
+		}
+
+		if isEdgesOnly(expr) {
+			errs = append(errs, errors.Errorf("%#v matches only empty input", expr))
+		}
+	}
+
+	return errs
+}
+
+// isEdgesOnly checks whether a pattern expression consists of only edges, such
+// as "^^$$". Such a pattern can match only empty input.
+func isEdgesOnly(expr string) bool {
+	return expr != "" && strings.Trim(expr, "^$") == ""
+}
+
+// FindDuplicatePatterns groups the indices of the expressions which are the
+// same after the macros are expanded. Such expressions compile to the same
+// Pattern. Only the groups with 2 or more indices are returned.
+func FindDuplicatePatterns(
+	exprs []string,
+
+	macros map[string]string,
+	vars map[string][]string,
+
+) [][]int {
+
+	var groups [][]int
+	expander := newMacroExpander(macros)
+	index := make(map[string]int)
+
+	for i, expr := range exprs {
+		if _, err := newPattern(expr, macros, vars); err != nil {
+			// Not a Pattern at all.
+			continue
+		}
+
+		expanded := expander.Replace(expr)
+
+		g, ok := index[expanded]
+		if !ok {
+			index[expanded] = len(groups)
+			groups = append(groups, []int{i})
+			continue
+		}
+
+		groups[g] = append(groups[g], i)
+	}
+
+	var dups [][]int
+	for _, g := range groups {
+		if len(g) > 1 {
+			dups = append(dups, g)
+		}
+	}
+
+	return dups
+}
+
+// newMacroExpander creates a Replacer to expand macros in expressions.
+func newMacroExpander(macros map[string]string) *strings.Replacer {
+	var args []string
+	for src, dst := range macros {
+		args = append(args, src, dst)
+	}
+	return strings.NewReplacer(args...)
+}
+
+// -----------------------------------------------------------------------------
+
+// ShadowReport reports the positions in a word where earlier rules have
+// consumed the letters which the rule would have matched.
+type ShadowReport struct {
+	Rule      Rule
+	Word      string
+	Positions []int
+}
+
+// FindShadowed applies the rules to each word in the corpus in the same manner
+// as the Transcribe step and reports the shadowed rules.
+//
+// The consumed letters are marked with NULL characters of the same length,
+// so positions are offsets in the original word.
+//
+func FindShadowed(rules []Rule, corpus []string) []ShadowReport {
+	var reports []ShadowReport
+
+	for _, word := range corpus {
+		dummy := subword.NewReplacer(word, 0, 0)
+		remaining := word
+
+		for _, rule := range rules {
+			var positions []int
+
+			for _, m := range rule.From.Find(word, -1) {
+				if strings.ContainsRune(remaining[m[0]:m[1]], '\x00') {
+					positions = append(positions, m[0])
+				}
+			}
+
+			if len(positions) != 0 {
+				reports = append(reports, ShadowReport{rule, word, positions})
+			}
+
+			for _, repl := range rule.replacements(remaining) {
+				nulls := strings.Repeat("\x00", repl.Stop-repl.Start)
+				dummy.Replace(repl.Start, repl.Stop, nulls)
+			}
+
+			remaining = dummy.String()
+		}
+	}
+
+	return reports
+}
@@ -0,0 +1,93 @@
+package hangulize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSpec(t *testing.T) {
+	macros := map[string]string{"@": "<vowels>"}
+	vars := map[string][]string{"vowels": {"a", "e"}}
+
+	errs := ValidateSpec([]string{
+		"b<vowels>",
+		"@b",
+		"<consonants>a",
+		"@<semivowels>",
+	}, macros, vars)
+
+	if assert.Len(t, errs, 2) {
+		assert.Contains(t, errs[0].Error(), "consonants")
+		assert.Contains(t, errs[1].Error(), "semivowels")
+	}
+
+	errs = ValidateSpec([]string{"b<vowels>", "@b"}, macros, vars)
+	assert.Len(t, errs, 0)
+
+	// The same unbalanced macro as ParseSpec rejects.
+	macros["%"] = "{<vowels>"
+
+	errs = ValidateSpec([]string{"b<vowels>"}, macros, vars)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "unbalanced lookaround braces")
+	}
+}
+
+func TestValidateSpecUnusablePattern(t *testing.T) {
+	errs := ValidateSpec([]string{"{~.*}@_@", "^^$$", "foo"}, nil, nil)
+
+	if assert.Len(t, errs, 2) {
+		assert.Contains(t, errs[0].Error(), "unlimited negative lookaround")
+		assert.Contains(t, errs[1].Error(), "only empty input")
+	}
+}
+
+func TestValidateSpecEdgesOnly(t *testing.T) {
+	exprs := []string{"^^$$", "^$", "^^$", "^$$", "^^abc$$"}
+	errs := ValidateSpec(exprs, nil, nil)
+
+	if assert.Len(t, errs, 4) {
+		for i, err := range errs {
+			assert.Contains(t, err.Error(), exprs[i])
+			assert.Contains(t, err.Error(), "only empty input")
+		}
+	}
+}
+
+func TestFindDuplicatePatterns(t *testing.T) {
+	macros := map[string]string{"@": "<vowels>"}
+	vars := map[string][]string{"vowels": {"a", "e"}}
+
+	dups := FindDuplicatePatterns([]string{
+		"@b",
+		"<vowels>b",
+		"ab",
+		"c",
+		"@b",
+		"c",
+		"d",
+	}, macros, vars)
+
+	assert.Equal(t, [][]int{{0, 1, 4}, {3, 5}}, dups)
+}
+
+func TestFindShadowed(t *testing.T) {
+	rules := []Rule{
+		newRule(0, "ab", "ㅂ"),
+		newRule(1, "b", "ㅍ"),
+		newRule(2, "c", "ㅋ"),
+	}
+
+	reports := FindShadowed(rules, []string{"abc", "bc", "cabab"})
+
+	if assert.Len(t, reports, 2) {
+		assert.Equal(t, 1, reports[0].Rule.ID)
+		assert.Equal(t, "abc", reports[0].Word)
+		assert.Equal(t, []int{1}, reports[0].Positions)
+
+		assert.Equal(t, 1, reports[1].Rule.ID)
+		assert.Equal(t, "cabab", reports[1].Word)
+		assert.Equal(t, []int{2, 4}, reports[1].Positions)
+	}
+}
@@ -0,0 +1,40 @@
+package hangulize
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeConfig configures the input normalization. It runs at the
+// beginning of the Normalize step, before any rule matches.
+type NormalizeConfig struct {
+	// Unicode applies the Unicode normalization Form.
+	Unicode bool
+	Form    norm.Form
+
+	// FoldCase converts letters into lower case.
+	FoldCase bool
+
+	// CollapseSpace trims spaces and collapses consecutive spaces into a
+	// single " ".
+	CollapseSpace bool
+}
+
+// Normalize normalizes a word by the config.
+func (c NormalizeConfig) Normalize(word string) string {
+	if c.Unicode {
+		word = c.Form.String(word)
+	}
+
+	if c.FoldCase {
+		word = strings.Map(unicode.ToLower, word)
+	}
+
+	if c.CollapseSpace {
+		word = strings.Join(strings.Fields(word), " ")
+	}
+
+	return word
+}
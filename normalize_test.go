@@ -0,0 +1,35 @@
+package hangulize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestNormalizeConfig(t *testing.T) {
+	var c NormalizeConfig
+	assert.Equal(t, " Café  Au  Lait ", c.Normalize(" Café  Au  Lait "))
+
+	c = NormalizeConfig{Unicode: true, Form: norm.NFC}
+	assert.Equal(t, "\u00e9", c.Normalize("e\u0301"))
+
+	c = NormalizeConfig{FoldCase: true}
+	assert.Equal(t, " café  au  lait ", c.Normalize(" Café  Au  Lait "))
+
+	c = NormalizeConfig{CollapseSpace: true}
+	assert.Equal(t, "Café Au Lait", c.Normalize(" Café  Au  Lait "))
+}
+
+func TestInputNormalizer(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a b" -> "ㅂ"
+	`)
+	h := NewHangulizer(spec)
+
+	assert.Equal(t, " ", h.Hangulize("a   b"))
+
+	h.SetInputNormalizer(&NormalizeConfig{CollapseSpace: true})
+	assert.Equal(t, "브", h.Hangulize("a   b"))
+}
@@ -0,0 +1,629 @@
+package hangulize
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/hangulize/hre"
+	"github.com/hangulize/stringset"
+)
+
+// NewDictionaryPattern creates a Pattern which matches only when the whole
+// word equals one of the given words. It is useful for exception dictionaries:
+//
+//   p, _ := NewDictionaryPattern([]string{"van", "von"})
+//
+func NewDictionaryPattern(words []string) (*hre.Pattern, error) {
+	if len(words) == 0 {
+		return nil, errors.New("dictionary pattern requires at least 1 word")
+	}
+
+	vars := map[string][]string{"words": words}
+	return hre.NewPattern("^^<words>$$", nil, vars)
+}
+
+// NewCatchAllPattern creates a Pattern which matches any single letter. Use it
+// in the last transcribe rule to handle the leftover letters.
+//
+// Transcribe marks the transcribed letters with NULL characters. The Pattern
+// never matches NULL, spaces, or punctuations so it consumes only the leftover
+// letters.
+//
+func NewCatchAllPattern() (*hre.Pattern, error) {
+	return hre.NewPattern(`[^\x00\s\pZ\pP]`, nil, nil)
+}
+
+// SampleResult reports whether a sample matched as expected.
+type SampleResult struct {
+	Sample   string
+	Expected bool
+	Actual   bool
+}
+
+// CheckSamples matches the Pattern with each sample. The samples map declares
+// whether each sample should match. It returns the mismatched results only,
+// sorted by sample.
+func CheckSamples(p *hre.Pattern, samples map[string]bool) []SampleResult {
+	var results []SampleResult
+
+	for sample, expected := range samples {
+		actual := len(p.Find(sample, 1)) != 0
+
+		if actual != expected {
+			results = append(results, SampleResult{sample, expected, actual})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Sample < results[j].Sample
+	})
+
+	return results
+}
+
+// Match is a structured match of a Pattern. Start and Stop indicate the
+// matched range. Groups keep the ranges of the submatches. A group which
+// didn't participate in the match has [-1, -1].
+type Match struct {
+	Start  int
+	Stop   int
+	Groups [][2]int
+}
+
+// String returns the matched part of the word.
+func (m Match) String(word string) string {
+	return word[m.Start:m.Stop]
+}
+
+// GroupString returns the part of the word matched by the ith group. It
+// returns an empty string if the group didn't participate in the match.
+func (m Match) GroupString(word string, i int) string {
+	g := m.Groups[i]
+	if g[0] < 0 {
+		return ""
+	}
+	return word[g[0]:g[1]]
+}
+
+// FindMatches works like Pattern.Find but returns Matches instead of raw
+// []int slices.
+func FindMatches(p *hre.Pattern, word string, n int) []Match {
+	var matches []Match
+
+	for _, m := range p.Find(word, n) {
+		groups := make([][2]int, 0, len(m)/2-1)
+		for i := 2; i+1 < len(m); i += 2 {
+			groups = append(groups, [2]int{m[i], m[i+1]})
+		}
+
+		matches = append(matches, Match{m[0], m[1], groups})
+	}
+
+	return matches
+}
+
+// FindPerChunk works like Pattern.Find but n limits the number of matches per
+// chunk instead of the whole word. Chunks are separated by spaces.
+func FindPerChunk(p *hre.Pattern, word string, n int) [][]int {
+	var matches [][]int
+	counts := make(map[int]int)
+
+	for _, m := range p.Find(word, -1) {
+		chunk := chunkIndex(word, m[0])
+
+		if n >= 0 && counts[chunk] >= n {
+			continue
+		}
+
+		counts[chunk]++
+		matches = append(matches, m)
+	}
+
+	return matches
+}
+
+// chunkIndex returns the index of the space-separated chunk which includes
+// the offset.
+func chunkIndex(word string, offset int) int {
+	chunk := 0
+	space := false
+
+	for i, ch := range word {
+		if i > offset {
+			break
+		}
+
+		if space && !unicode.IsSpace(ch) {
+			chunk++
+		}
+		space = unicode.IsSpace(ch)
+	}
+
+	return chunk
+}
+
+// MatchedRuneFrequency counts the characters in the matched parts of the
+// words.
+func MatchedRuneFrequency(p *hre.Pattern, words []string) map[rune]int {
+	freq := make(map[rune]int)
+
+	for _, word := range words {
+		for _, m := range p.Find(word, -1) {
+			for _, ch := range word[m[0]:m[1]] {
+				freq[ch]++
+			}
+		}
+	}
+
+	return freq
+}
+
+// DelimPair is a pair of opening and closing delimiters, such as "(" and ")".
+type DelimPair struct {
+	Open  string
+	Close string
+}
+
+// FindOutside works like Pattern.Find but skips the matches overlapping
+// delimited ranges. An unclosed delimiter lasts until the end of the word.
+func FindOutside(p *hre.Pattern, word string, delims []DelimPair, n int) [][]int {
+	var matches [][]int
+	ranges := delimitedRanges(word, delims)
+
+	for _, m := range p.Find(word, -1) {
+		if n >= 0 && len(matches) >= n {
+			break
+		}
+
+		inside := false
+		for _, r := range ranges {
+			if m[0] < r[1] && r[0] < m[1] {
+				inside = true
+				break
+			}
+		}
+
+		if !inside {
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+// delimitedRanges finds the ranges enclosed by the delimiters. The ranges
+// include the delimiters themselves.
+func delimitedRanges(word string, delims []DelimPair) [][2]int {
+	var ranges [][2]int
+
+	i := 0
+	for i < len(word) {
+		stop := -1
+
+		for _, d := range delims {
+			if d.Open == "" || !strings.HasPrefix(word[i:], d.Open) {
+				continue
+			}
+
+			from := i + len(d.Open)
+			stop = strings.Index(word[from:], d.Close)
+
+			if d.Close == "" || stop == -1 {
+				stop = len(word)
+			} else {
+				stop += from + len(d.Close)
+			}
+			break
+		}
+
+		if stop == -1 {
+			i++
+			continue
+		}
+
+		ranges = append(ranges, [2]int{i, stop})
+		i = stop
+	}
+
+	return ranges
+}
+
+// FindWithinChunks works like Pattern.Find but never returns a match crossing
+// any of the boundaries. A boundary is a byte offset in the word. A match may
+// start or stop at a boundary.
+//
+// A rejected match doesn't hide the other matches overlapping it. The search
+// restarts right after the start of the rejected match.
+//
+func FindWithinChunks(p *hre.Pattern, word string, boundaries []int, n int) [][]int {
+	var matches [][]int
+	from := 0
+
+search:
+	for {
+		for _, m := range findFrom(p, word, from) {
+			if n >= 0 && len(matches) >= n {
+				break search
+			}
+
+			crossing := false
+			for _, b := range boundaries {
+				if m[0] < b && b < m[1] {
+					crossing = true
+					break
+				}
+			}
+
+			if crossing {
+				from = nextRuneOffset(word, m[0])
+				continue search
+			}
+
+			matches = append(matches, m)
+		}
+		break
+	}
+
+	return matches
+}
+
+// findFrom finds the matches which start at or after the byte offset. The word
+// before the offset is masked with NULL characters, as the Transcribe step
+// masks the transcribed letters. So the offsets are kept.
+func findFrom(p *hre.Pattern, word string, from int) [][]int {
+	if from == 0 {
+		return p.Find(word, -1)
+	}
+
+	masked := strings.Repeat("\x00", from) + word[from:]
+	return p.Find(masked, -1)
+}
+
+// nextRuneOffset returns the byte offset of the rune after the rune at the
+// offset.
+func nextRuneOffset(word string, offset int) int {
+	_, size := utf8.DecodeRuneInString(word[offset:])
+	return offset + size
+}
+
+// MatchesToCSV writes every match of the Pattern in the words as CSV. Each row
+// consists of the word, the start and stop offsets, and the matched text. The
+// first row is the header.
+func MatchesToCSV(w io.Writer, p *hre.Pattern, words []string) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{"word", "start", "stop", "match"})
+	if err != nil {
+		return err
+	}
+
+	for _, word := range words {
+		for _, m := range p.Find(word, -1) {
+			start, stop := m[0], m[1]
+
+			row := []string{
+				word,
+				strconv.Itoa(start),
+				strconv.Itoa(stop),
+				word[start:stop],
+			}
+
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ShortestNonMatch returns the shortest candidate which the Pattern doesn't
+// match. The length is counted in runes. The earlier one wins a tie. It
+// returns false if the Pattern matches all candidates.
+func ShortestNonMatch(p *hre.Pattern, candidates []string) (string, bool) {
+	shortest := ""
+	found := false
+
+	for _, c := range candidates {
+		if found && utf8.RuneCountInString(c) >= utf8.RuneCountInString(shortest) {
+			continue
+		}
+
+		if len(p.Find(c, 1)) == 0 {
+			shortest = c
+			found = true
+		}
+	}
+
+	return shortest, found
+}
+
+// CompileRecord keeps the exact inputs of a Pattern compilation. It can be
+// serialized, such as by encoding/json, to reproduce the compilation later.
+type CompileRecord struct {
+	Expr   string
+	Macros map[string]string
+	Vars   map[string][]string
+}
+
+// RecordCompile compiles a Pattern and records the inputs. The record keeps
+// copies of the macros and vars. So it is not affected by the later changes
+// of them.
+func RecordCompile(
+	expr string,
+
+	macros map[string]string,
+	vars map[string][]string,
+
+) (*hre.Pattern, CompileRecord, error) {
+
+	var macrosCopy map[string]string
+	if macros != nil {
+		macrosCopy = make(map[string]string, len(macros))
+		for src, dst := range macros {
+			macrosCopy[src] = dst
+		}
+	}
+
+	var varsCopy map[string][]string
+	if vars != nil {
+		varsCopy = MapVars(vars, func(val string) string { return val })
+	}
+
+	record := CompileRecord{expr, macrosCopy, varsCopy}
+	p, err := ReplayCompile(record)
+	return p, record, err
+}
+
+// ReplayCompile compiles a Pattern from a record.
+func ReplayCompile(record CompileRecord) (*hre.Pattern, error) {
+	return hre.NewPattern(record.Expr, record.Macros, record.Vars)
+}
+
+// FindTransparent works like Pattern.Find but ignores the transparent
+// characters in the word, such as soft hyphens or zero-width joiners. The
+// offsets are in the original word, so a match spans the transparent
+// characters inside it.
+//
+// It only finds matches. Rule.ReplaceTransparent replaces them with a policy
+// for the transparent characters.
+//
+func FindTransparent(p *hre.Pattern, word string, transparent string, n int) [][]int {
+	_, matches, origins := findTransparent(p, word, transparent, n)
+
+	for _, m := range matches {
+		for i := 0; i+1 < len(m); i += 2 {
+			if m[i] < 0 {
+				// Not participated.
+				continue
+			}
+			m[i], m[i+1] = originSpan(origins, m[i], m[i+1])
+		}
+	}
+
+	return matches
+}
+
+// findTransparent finds matches in the word without the transparent
+// characters. It returns the filtered word, the matches in it, and the byte
+// offsets in the original word for each byte offset in the filtered word.
+func findTransparent(
+	p *hre.Pattern,
+	word string,
+	transparent string,
+	n int,
+) (string, [][]int, []int) {
+
+	var buf bytes.Buffer
+	var origins []int
+
+	for i, ch := range word {
+		if strings.ContainsRune(transparent, ch) {
+			continue
+		}
+
+		for j := 0; j < utf8.RuneLen(ch); j++ {
+			origins = append(origins, i+j)
+		}
+		buf.WriteRune(ch)
+	}
+	origins = append(origins, len(word))
+
+	filtered := buf.String()
+	return filtered, p.Find(filtered, n), origins
+}
+
+// originSpan converts a span in the filtered word into the original word.
+func originSpan(origins []int, start, stop int) (int, int) {
+	if start == stop {
+		return origins[start], origins[start]
+	}
+	return origins[start], origins[stop-1] + 1
+}
+
+// CompilePatternsFromReader compiles the Patterns line by line. Each line is
+// one expression. Blank lines and comment lines starting with "#" are
+// ignored. It keeps compiling after an error and reports the errors with the
+// line numbers.
+func CompilePatternsFromReader(
+	r io.Reader,
+
+	macros map[string]string,
+	vars map[string][]string,
+
+) ([]*hre.Pattern, []error) {
+
+	var patterns []*hre.Pattern
+	var errs []error
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		expr := strings.TrimSpace(scanner.Text())
+
+		if expr == "" || strings.HasPrefix(expr, "#") {
+			continue
+		}
+
+		p, err := hre.NewPattern(expr, macros, vars)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "line %d", lineNo))
+			continue
+		}
+
+		patterns = append(patterns, p)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return patterns, errs
+}
+
+// FindSafe works like Pattern.Find but returns an error instead of panicking.
+// The error includes the Pattern, the word, n, and the panic value to
+// reproduce the panic.
+//
+// The expanded regexps and the match slice at the panic, such as for the
+// "unexpected submatches" panic, are not included. They are kept inside
+// hre.Pattern and unreachable from here.
+//
+func FindSafe(p *hre.Pattern, word string, n int) (matches [][]int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			matches = nil
+			err = errors.Errorf(
+				"%s panicked on %#v with n=%d: %v", p, word, n, r)
+		}
+	}()
+
+	return p.Find(word, n), nil
+}
+
+// ForeignLetters returns the letters in the Pattern which are not in the
+// source alphabet. They are likely typos or misplaced target letters.
+func ForeignLetters(p *hre.Pattern, sourceAlphabet []string) []string {
+	var foreign []string
+	source := stringset.NewStringSet(sourceAlphabet...)
+
+	for _, let := range p.Letters() {
+		if !source.Has(let) {
+			foreign = append(foreign, let)
+		}
+	}
+
+	sort.Strings(foreign)
+	return foreign
+}
+
+// FindVarOccurrences finds all positions where any value of the var matches.
+// It returns nil if the var is not defined.
+func FindVarOccurrences(word string, varName string, vars map[string][]string) [][]int {
+	if _, ok := vars[varName]; !ok {
+		return nil
+	}
+
+	p, err := hre.NewPattern("<"+varName+">", nil, vars)
+	if err != nil {
+		return nil
+	}
+
+	var spans [][]int
+	for _, m := range p.Find(word, -1) {
+		spans = append(spans, []int{m[0], m[1]})
+	}
+	return spans
+}
+
+// FindMinGap works like Pattern.Find but skips a match which starts within
+// minGap runes from the stop of the previous accepted match. It supports
+// dissimilation-style rules which shouldn't fire twice too close together.
+//
+// A skipped match doesn't hide the other matches overlapping it. The search
+// restarts right after the start of the skipped match.
+//
+func FindMinGap(p *hre.Pattern, word string, minGap int, n int) [][]int {
+	var matches [][]int
+	prevStop := -1
+	from := 0
+
+search:
+	for {
+		for _, m := range findFrom(p, word, from) {
+			if n >= 0 && len(matches) >= n {
+				break search
+			}
+
+			if prevStop >= 0 {
+				gap := utf8.RuneCountInString(word[prevStop:m[0]])
+
+				if gap < minGap {
+					if m[0] >= len(word) {
+						break search
+					}
+
+					from = nextRuneOffset(word, m[0])
+					continue search
+				}
+			}
+
+			matches = append(matches, m)
+			prevStop = m[1]
+		}
+		break
+	}
+
+	return matches
+}
+
+// MatchTimer records the wall-clock time spent by Pattern.Find. It profiles
+// which Patterns dominate the transcription time. Only the Finds through a
+// MatchTimer are timed, so there is no overhead otherwise.
+type MatchTimer struct {
+	durations map[string]time.Duration
+	last      time.Duration
+}
+
+// NewMatchTimer creates an empty MatchTimer.
+func NewMatchTimer() *MatchTimer {
+	return &MatchTimer{make(map[string]time.Duration), 0}
+}
+
+// Find calls Pattern.Find and records the time spent.
+func (t *MatchTimer) Find(p *hre.Pattern, word string, n int) [][]int {
+	started := time.Now()
+	matches := p.Find(word, n)
+	t.last = time.Since(started)
+
+	t.durations[p.String()] += t.last
+	return matches
+}
+
+// LastMatchDuration returns the time spent by the last Find.
+func (t *MatchTimer) LastMatchDuration() time.Duration {
+	return t.last
+}
+
+// Durations returns the total time spent per Pattern. The keys are the
+// Pattern expressions.
+func (t *MatchTimer) Durations() map[string]time.Duration {
+	durations := make(map[string]time.Duration, len(t.durations))
+	for expr, d := range t.durations {
+		durations[expr] = d
+	}
+	return durations
+}
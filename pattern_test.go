@@ -0,0 +1,394 @@
+package hangulize
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hangulize/hre"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictionaryPattern(t *testing.T) {
+	p, err := NewDictionaryPattern([]string{"van", "von"})
+	assert.NoError(t, err)
+
+	assert.Len(t, p.Find("van", -1), 1)
+	assert.Len(t, p.Find("von", -1), 1)
+
+	// Only exact whole-word matches.
+	assert.Len(t, p.Find("vans", -1), 0)
+	assert.Len(t, p.Find("avon", -1), 0)
+	assert.Len(t, p.Find("vin", -1), 0)
+}
+
+func TestEmptyDictionaryPattern(t *testing.T) {
+	_, err := NewDictionaryPattern(nil)
+	assert.Error(t, err)
+}
+
+func TestCatchAllPattern(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅇ"
+	`)
+
+	p, err := NewCatchAllPattern()
+	assert.NoError(t, err)
+
+	rp := hre.NewRPattern("ㅋ", nil, nil)
+	spec.Transcribe = append(spec.Transcribe, Rule{1, p, rp})
+
+	// The catch-all rule consumes only the leftover "b".
+	assert.Equal(t, "으크", hangulize(spec, "ab"))
+	assert.Equal(t, "크으크", hangulize(spec, "bab"))
+
+	// Spaces between words are not consumed.
+	assert.Equal(t, "으크 으크", hangulize(spec, "ab ab"))
+}
+
+func TestCheckSamples(t *testing.T) {
+	p, _ := hre.NewPattern("foo", nil, nil)
+
+	results := CheckSamples(p, map[string]bool{
+		"foo":    true,
+		"foobar": true,
+		"bar":    false,
+		"fo":     true,  // mismatch
+		"barfoo": false, // mismatch
+	})
+
+	assert.Equal(t, []SampleResult{
+		{"barfoo", false, true},
+		{"fo", true, false},
+	}, results)
+}
+
+func TestFindMatches(t *testing.T) {
+	vars := map[string][]string{"abc": {"a", "b", "c"}}
+	p, _ := hre.NewPattern("x<abc>", nil, vars)
+
+	word := "xaxbyxc"
+	matches := FindMatches(p, word, -1)
+	raw := p.Find(word, -1)
+
+	assert.Len(t, matches, len(raw))
+
+	for i, m := range matches {
+		assert.Equal(t, raw[i][0], m.Start)
+		assert.Equal(t, raw[i][1], m.Stop)
+		assert.Len(t, m.Groups, len(raw[i])/2-1)
+
+		for j, g := range m.Groups {
+			assert.Equal(t, raw[i][2+j*2], g[0])
+			assert.Equal(t, raw[i][3+j*2], g[1])
+		}
+	}
+
+	assert.Equal(t, "xa", matches[0].String(word))
+	assert.Equal(t, "xb", matches[1].String(word))
+	assert.Equal(t, "xc", matches[2].String(word))
+}
+
+func TestFindPerChunk(t *testing.T) {
+	p, _ := hre.NewPattern("o", nil, nil)
+
+	assert.Len(t, p.Find("foo boo zoo", 1), 1)
+
+	matches := FindPerChunk(p, "foo boo zoo", 1)
+	if assert.Len(t, matches, 3) {
+		assert.Equal(t, 1, matches[0][0])
+		assert.Equal(t, 5, matches[1][0])
+		assert.Equal(t, 9, matches[2][0])
+	}
+
+	matches = FindPerChunk(p, "foo boo zoo", -1)
+	assert.Len(t, matches, 6)
+}
+
+func TestMatchedRuneFrequency(t *testing.T) {
+	vars := map[string][]string{"vowels": {"a", "e", "ö"}}
+	p, _ := hre.NewPattern("l<vowels>", nil, vars)
+
+	freq := MatchedRuneFrequency(p, []string{"lala", "leö", "löwe", "xyz"})
+
+	assert.Equal(t, map[rune]int{
+		'l': 4,
+		'a': 2,
+		'e': 1,
+		'ö': 1,
+	}, freq)
+}
+
+func TestFindOutside(t *testing.T) {
+	p, _ := hre.NewPattern("foo", nil, nil)
+	delims := []DelimPair{{`"`, `"`}, {"(", ")"}}
+
+	matches := FindOutside(p, `foo "foo" (foo) foo`, delims, -1)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, 0, matches[0][0])
+		assert.Equal(t, 16, matches[1][0])
+	}
+
+	// Limited.
+	matches = FindOutside(p, `foo "foo" (foo) foo`, delims, 1)
+	assert.Len(t, matches, 1)
+
+	// Unclosed.
+	matches = FindOutside(p, `foo (foo foo`, delims, -1)
+	assert.Len(t, matches, 1)
+}
+
+func TestFindWithinChunks(t *testing.T) {
+	p, _ := hre.NewPattern("ab", nil, nil)
+
+	// "xa|bab|ab"
+	word := "xababab"
+	boundaries := []int{2, 5}
+
+	assert.Len(t, p.Find(word, -1), 3)
+
+	matches := FindWithinChunks(p, word, boundaries, -1)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, 3, matches[0][0])
+		assert.Equal(t, 5, matches[1][0])
+	}
+
+	assert.Len(t, FindWithinChunks(p, word, nil, -1), 3)
+	assert.Len(t, FindWithinChunks(p, word, boundaries, 1), 1)
+
+	// "a|aa": [0, 2) crosses the boundary but [1, 3) lies within a chunk.
+	p, _ = hre.NewPattern("aa", nil, nil)
+
+	matches = FindWithinChunks(p, "aaa", []int{1}, -1)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, 1, matches[0][0])
+		assert.Equal(t, 3, matches[0][1])
+	}
+}
+
+func TestMatchesToCSV(t *testing.T) {
+	p, _ := hre.NewPattern("o,", nil, nil)
+
+	var b bytes.Buffer
+	err := MatchesToCSV(&b, p, []string{"foo, boo, bar", "boo"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, `word,start,stop,match
+"foo, boo, bar",2,4,"o,"
+"foo, boo, bar",7,9,"o,"
+`, b.String())
+}
+
+func TestShortestNonMatch(t *testing.T) {
+	p, _ := hre.NewPattern("a", nil, nil)
+
+	nonMatch, ok := ShortestNonMatch(p, []string{"banana", "xyz", "ab", "éé", "a"})
+	assert.True(t, ok)
+	assert.Equal(t, "éé", nonMatch)
+
+	_, ok = ShortestNonMatch(p, []string{"banana", "ab", "a"})
+	assert.False(t, ok)
+}
+
+func TestReplayCompile(t *testing.T) {
+	macros := map[string]string{"@": "<vowels>"}
+	vars := map[string][]string{"vowels": {"a", "e"}}
+
+	p, record, err := RecordCompile("b@", macros, vars)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(record)
+	assert.NoError(t, err)
+
+	var loaded CompileRecord
+	err = json.Unmarshal(data, &loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, record, loaded)
+
+	replayed, err := ReplayCompile(loaded)
+	assert.NoError(t, err)
+
+	assert.Equal(t, p.String(), replayed.String())
+	assert.ElementsMatch(t, p.Letters(), replayed.Letters())
+
+	for _, word := range []string{"ba", "abeb", "bi"} {
+		assert.Equal(t, p.Find(word, -1), replayed.Find(word, -1))
+	}
+}
+
+func TestRecordCompileCopies(t *testing.T) {
+	macros := map[string]string{"@": "<vowels>"}
+	vars := map[string][]string{"vowels": {"a", "e"}}
+
+	_, record, err := RecordCompile("b@", macros, vars)
+	assert.NoError(t, err)
+
+	// Mutate the inputs after recording.
+	macros["@"] = "<consonants>"
+	macros["%"] = "<vowels>"
+	vars["vowels"][0] = "i"
+	vars["consonants"] = []string{"b"}
+
+	assert.Equal(t, map[string]string{"@": "<vowels>"}, record.Macros)
+	assert.Equal(t, map[string][]string{"vowels": {"a", "e"}}, record.Vars)
+}
+
+func TestFindTransparent(t *testing.T) {
+	p, _ := hre.NewPattern("ab", nil, nil)
+
+	// Soft hyphen between "a" and "b".
+	word := "xa\u00ADby ab"
+	assert.Len(t, p.Find(word, -1), 1)
+
+	matches := FindTransparent(p, word, "\u00AD\u200D", -1)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, "a\u00ADb", word[matches[0][0]:matches[0][1]])
+		assert.Equal(t, "ab", word[matches[1][0]:matches[1][1]])
+	}
+}
+
+func TestCompilePatternsFromReader(t *testing.T) {
+	r := strings.NewReader(`foo
+# comment
+
+  bar
+ba(z
+qux
+`)
+
+	patterns, errs := CompilePatternsFromReader(r, nil, nil)
+
+	assert.Len(t, patterns, 3)
+
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "line 5")
+	}
+}
+
+func TestFindSafe(t *testing.T) {
+	p, _ := hre.NewPattern("o", nil, nil)
+
+	matches, err := FindSafe(p, "foo", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Find("foo", -1), matches)
+
+	// The "unexpected submatches" panic can't be provoked from outside of
+	// hre. A nil Pattern panics in Find instead. It covers only the recovery.
+	var broken *hre.Pattern
+	assert.Panics(t, func() { broken.Find("foo", -1) })
+
+	matches, err = FindSafe(broken, "foo", -1)
+	assert.Nil(t, matches)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"foo"`)
+	}
+}
+
+func TestAstralLetters(t *testing.T) {
+	// U+1D538 MATHEMATICAL DOUBLE-STRUCK CAPITAL A takes 4 bytes in UTF-8.
+	p, err := hre.NewPattern("𝔸", nil, nil)
+	assert.NoError(t, err)
+
+	spans := func(word string) [][2]int {
+		var spans [][2]int
+		for _, m := range p.Find(word, -1) {
+			spans = append(spans, [2]int{m[0], m[1]})
+		}
+		return spans
+	}
+
+	assert.Equal(t, [][2]int{{1, 5}}, spans("a𝔸b"))
+	assert.Contains(t, p.Letters(), "𝔸")
+
+	// Lookarounds erase the buffer around astral letters without splitting.
+	p, err = hre.NewPattern("{𝔸}b{~𝔸}", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, [][2]int{{4, 5}, {10, 11}}, spans("𝔸bb𝔸b"))
+
+	r := newRule(0, "𝔸", "ㅇ")
+	assert.Equal(t, "aㅇb", r.Replace("a𝔸b"))
+}
+
+func TestForeignLetters(t *testing.T) {
+	p, err := hre.NewPattern("baㅂ", nil, nil)
+	assert.NoError(t, err)
+
+	alphabet := []string{"a", "b", "c"}
+	assert.Equal(t, []string{"ㅂ"}, ForeignLetters(p, alphabet))
+
+	p, err = hre.NewPattern("cab", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Nil(t, ForeignLetters(p, alphabet))
+}
+
+func TestFindVarOccurrences(t *testing.T) {
+	vars := map[string][]string{
+		"vowel": {"a", "e", "i", "o", "u"},
+	}
+
+	spans := FindVarOccurrences("hangulize", "vowel", vars)
+	assert.Equal(t, [][]int{{1, 2}, {4, 5}, {6, 7}, {8, 9}}, spans)
+
+	assert.Nil(t, FindVarOccurrences("hangulize", "consonant", vars))
+	assert.Nil(t, FindVarOccurrences("xyz", "vowel", vars))
+}
+
+func TestFindMinGap(t *testing.T) {
+	p, err := hre.NewPattern("r", nil, nil)
+	assert.NoError(t, err)
+
+	starts := func(matches [][]int) []int {
+		var starts []int
+		for _, m := range matches {
+			starts = append(starts, m[0])
+		}
+		return starts
+	}
+
+	// "peregrina" has two letters between the r's.
+	assert.Equal(t, []int{2, 5}, starts(FindMinGap(p, "peregrina", 0, -1)))
+	assert.Equal(t, []int{2, 5}, starts(FindMinGap(p, "peregrina", 2, -1)))
+	assert.Equal(t, []int{2}, starts(FindMinGap(p, "peregrina", 3, -1)))
+
+	// The gap is counted in runes.
+	assert.Equal(t, []int{0, 4}, starts(FindMinGap(p, "rérr", 2, -1)))
+	assert.Equal(t, []int{0, 3}, starts(FindMinGap(p, "rérr", 1, -1)))
+
+	assert.Equal(t, []int{2}, starts(FindMinGap(p, "peregrina", 0, 1)))
+
+	// [2, 4) is skipped but [3, 5) is far enough from [0, 2).
+	p, err = hre.NewPattern("aa", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{0, 2}, starts(FindMinGap(p, "aaaaa", 0, -1)))
+	assert.Equal(t, []int{0, 3}, starts(FindMinGap(p, "aaaaa", 1, -1)))
+	assert.Equal(t, []int{0}, starts(FindMinGap(p, "aaaaa", 2, -1)))
+}
+
+func TestMatchTimer(t *testing.T) {
+	p, _ := hre.NewPattern("ab", nil, nil)
+	q, _ := hre.NewPattern("ba", nil, nil)
+
+	timer := NewMatchTimer()
+	assert.Equal(t, time.Duration(0), timer.LastMatchDuration())
+	assert.Len(t, timer.Durations(), 0)
+
+	word := strings.Repeat("ab", 1000)
+
+	matches := timer.Find(p, word, -1)
+	assert.Equal(t, p.Find(word, -1), matches)
+	assert.True(t, timer.LastMatchDuration() > 0)
+
+	timer.Find(p, word, -1)
+	timer.Find(q, word, -1)
+
+	durations := timer.Durations()
+	assert.Len(t, durations, 2)
+	assert.True(t, durations[p.String()] > 0)
+	assert.True(t, durations[q.String()] > 0)
+}
@@ -3,8 +3,12 @@ package hangulize
 import (
 	"bytes"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
 
 	"github.com/hangulize/hangulize/internal/jamo"
 	"github.com/hangulize/hangulize/internal/subword"
@@ -83,26 +87,94 @@ func (s Step) String() string {
 type pipeline struct {
 	h  *Hangulizer
 	tr *tracer
+
+	// cov collects the transcribe coverage if not nil.
+	cov *coverage
+
+	// limit is the maximum runes in the transcribing phase. 0 means
+	// unlimited. It is determined by forward.
+	limit int
+}
+
+// coverage collects how many letters in the normalized word have been
+// transcribed. It tracks which letters each byte derives from, through the
+// Rewrite and Transcribe steps.
+type coverage struct {
+	letters int    // The number of letters to transcribe.
+	residue []rune // The untranscribed letters.
+
+	// input keeps the letters to transcribe by their byte offsets in the
+	// normalized word.
+	input map[int]rune
+
+	// origins maps each byte in the subwords to the byte offsets of the
+	// letters which it derives from.
+	origins [][]int
+
+	derived     map[int]bool // The letters which have remained.
+	transcribed map[int]bool // The letters which have been transcribed.
 }
 
-// forward runs the Hangulize pipeline for a word.
-func (p pipeline) forward(word string) string {
+// forward runs the Hangulize pipeline for a word. It fails if the word expands
+// over the max expansion ratio of the Hangulizer.
+func (p pipeline) forward(word string) (string, error) {
 	p.input(word)
 
+	if p.h.maxExpansion > 0 {
+		p.limit = expansionLimit(word, p.h.maxExpansion)
+	}
+	input := word
+
 	// preparing phase
 	word, _ = p.phonemize(word)
 	word = p.normalize(word)
 
 	// transcribing phase
 	subwords := p.group(word)
+	p.trackLetters(subwords)
+
 	subwords = p.rewrite(subwords)
+	if p.exceeds(subwords) {
+		return "", p.expansionError(input, Rewrite)
+	}
+
 	subwords = p.transcribe(subwords)
+	if p.exceeds(subwords) {
+		return "", p.expansionError(input, Transcribe)
+	}
 
 	// finalizing phase
 	word = p.syllabify(subwords)
 	word = p.transliterate(word)
 
-	return word
+	return word, nil
+}
+
+// exceeds checks whether the subwords have more runes than the limit. Level=0
+// subwords are not counted because they just pass through.
+func (p pipeline) exceeds(subwords []subword.Subword) bool {
+	if p.limit == 0 {
+		return false
+	}
+
+	n := 0
+	for _, sw := range subwords {
+		if sw.Level != 0 {
+			n += countExpanded(sw.Word)
+		}
+	}
+	return n > p.limit
+}
+
+// countExpanded counts the runes in a word to compare with the limit. The
+// tail markers ("-") are not counted because they never remain in the output.
+func countExpanded(word string) int {
+	return utf8.RuneCountInString(word) - strings.Count(word, "-")
+}
+
+func (p pipeline) expansionError(word string, step Step) error {
+	return errors.Errorf(
+		"%#v expanded over %g times at %s", word, p.h.maxExpansion, step)
 }
 
 // -----------------------------------------------------------------------------
@@ -155,7 +227,16 @@ PhonemizerFound:
 //
 // For example, "Hello" in Latin script will be normalized to "hello".
 //
+// The Unicode form is kept as is. Set an input normalizer with NFC to match a
+// decomposed letter, such as "e\u0301", as the precomposed "é".
+//
 func (p pipeline) normalize(word string) string {
+	// Input normalization.
+	if p.h.inputNorm != nil {
+		word = p.h.inputNorm.Normalize(word)
+		p.tr.Trace(Normalize, word, "input")
+	}
+
 	// Per-spec normalization.
 	word = p.h.spec.normReplacer.Replace(word)
 
@@ -191,6 +272,9 @@ func (p pipeline) normalize(word string) string {
 // For example, "hello, world!" will be grouped into
 // [{"hello",1}, {", ",0}, {"world",1}, {"!",0}].
 //
+// The letters in the script of the fallback spec are meaningful too. So the
+// fallback rules can transcribe them.
+//
 func (p pipeline) group(word string) []subword.Subword {
 	rep := subword.NewReplacer(word, 0, 1)
 
@@ -200,6 +284,8 @@ func (p pipeline) group(word string) []subword.Subword {
 		switch {
 		case p.h.spec.script.Is(ch):
 			fallthrough
+		case p.h.fallback != nil && p.h.fallback.script.Is(ch):
+			fallthrough
 		case p.h.spec.puncts.HasRune(ch):
 			fallthrough
 		case isSpace(let):
@@ -225,10 +311,16 @@ func (p pipeline) rewrite(subwords []subword.Subword) []subword.Subword {
 
 	swtr := p.tr.SubwordsTracer(Rewrite, subwords)
 
+	var origins [][]int
+	offset := 0
+
 	for i, sw := range subwords {
 		word := sw.Word
 		level := sw.Level
 
+		segment := p.originsOf(offset, len(word))
+		offset += len(word)
+
 		rep := subword.NewReplacer(word, level, 1)
 
 		for _, rule := range p.h.spec.Rewrite {
@@ -236,14 +328,28 @@ func (p pipeline) rewrite(subwords []subword.Subword) []subword.Subword {
 			rep.ReplaceBy(repls...)
 			word = rep.String()
 
+			if p.cov != nil {
+				segment = replaceOrigins(segment, repls)
+			}
+
 			swtr.Trace(i, word, rule)
+
+			if p.limit != 0 && level != 0 && countExpanded(word) > p.limit {
+				// Stop here. forward will report it.
+				break
+			}
 		}
 
 		swBuf.Write(rep.Subwords()...)
+		origins = append(origins, segment...)
 	}
 
 	subwords = swBuf.Subwords()
 
+	if p.cov != nil {
+		p.cov.origins = origins
+	}
+
 	swtr.Commit()
 
 	return subwords
@@ -263,16 +369,23 @@ func (p pipeline) transcribe(subwords []subword.Subword) []subword.Subword {
 	var swBuf subword.Builder
 
 	swtr := p.tr.SubwordsTracer(Transcribe, subwords)
+	rules := p.transcribeRules()
+
+	offset := 0
 
 	for i, sw := range subwords {
-		if sw.Level == 0 {
+		word := sw.Word
+		level := sw.Level
+
+		segment := p.originsOf(offset, len(word))
+		offset += len(word)
+
+		if level == 0 {
+			p.settleLetters(segment, "")
 			swBuf.Write(sw)
 			continue
 		}
 
-		word := sw.Word
-		level := sw.Level
-
 		rep := subword.NewReplacer(word, level, 2)
 
 		// transcribe is not rewrite. A result of a replacement is not the
@@ -280,7 +393,7 @@ func (p pipeline) transcribe(subwords []subword.Subword) []subword.Subword {
 		// with NULL characters.
 		dummy := subword.NewReplacer(word, 0, 0)
 
-		for _, rule := range p.h.spec.Transcribe {
+		for _, rule := range rules {
 			repls := rule.replacements(word)
 			rep.ReplaceBy(repls...)
 
@@ -289,13 +402,20 @@ func (p pipeline) transcribe(subwords []subword.Subword) []subword.Subword {
 				dummy.Replace(repl.Start, repl.Stop, nulls)
 			}
 
+			if p.cov != nil {
+				segment = replaceOrigins(segment, repls)
+			}
+
 			word = dummy.String()
 			swtr.Trace(i, rep.String(), rule)
 		}
 
+		p.settleLetters(segment, word)
 		swBuf.Write(rep.Subwords()...)
 	}
 
+	p.collectResidue()
+
 	// Discard level=1 subwords. They have been generated by "3. Rewrite" but
 	// never transcribed. They are superfluity of the internal behavior.
 	subwords = swBuf.Subwords()
@@ -303,6 +423,11 @@ func (p pipeline) transcribe(subwords []subword.Subword) []subword.Subword {
 
 	for _, sw := range subwords {
 		if sw.Level == 1 {
+			if p.h.placeholder != "" {
+				swBuf.Write(p.placehold(sw.Word)...)
+				continue
+			}
+
 			if hasSpace(sw.Word) {
 				swBuf.Write(subword.New(" ", 1))
 			}
@@ -318,6 +443,176 @@ func (p pipeline) transcribe(subwords []subword.Subword) []subword.Subword {
 	return subwords
 }
 
+// trackLetters starts to track the letters to transcribe in the grouped
+// subwords. Spaces and punctuations are not letters to transcribe. The letters
+// in level=0 subwords are counted too. They are out of the script so they will
+// never be transcribed.
+func (p pipeline) trackLetters(subwords []subword.Subword) {
+	if p.cov == nil {
+		return
+	}
+
+	p.cov.input = make(map[int]rune)
+	p.cov.origins = nil
+	p.cov.derived = make(map[int]bool)
+	p.cov.transcribed = make(map[int]bool)
+
+	offset := 0
+
+	for _, sw := range subwords {
+		for i, ch := range sw.Word {
+			var origin []int
+
+			if isLetterToTranscribe(ch, sw.Level) {
+				p.cov.input[offset+i] = ch
+				origin = []int{offset + i}
+			}
+
+			for j := 0; j < utf8.RuneLen(ch); j++ {
+				p.cov.origins = append(p.cov.origins, origin)
+			}
+		}
+		offset += len(sw.Word)
+	}
+
+	p.cov.letters = len(p.cov.input)
+}
+
+// originsOf returns the origins of a subword at the byte offset.
+func (p pipeline) originsOf(offset, length int) [][]int {
+	if p.cov == nil {
+		return nil
+	}
+	return p.cov.origins[offset : offset+length]
+}
+
+// replaceOrigins applies the replacements to the origins in the same way as
+// subword.Replacer does to the word. Each byte of a replacement derives from
+// all letters in the replaced range.
+func replaceOrigins(origins [][]int, repls []subword.Replacement) [][]int {
+	var replaced [][]int
+	offset := 0
+
+	for _, repl := range repls {
+		replaced = append(replaced, origins[offset:repl.Start]...)
+
+		var merged []int
+		for _, origin := range origins[repl.Start:repl.Stop] {
+			merged = append(merged, origin...)
+		}
+
+		for i := 0; i < len(repl.Word); i++ {
+			replaced = append(replaced, merged)
+		}
+
+		offset = repl.Stop
+	}
+
+	return append(replaced, origins[offset:]...)
+}
+
+// settleLetters marks the letters which the bytes of a subword derive from.
+// dummy is the subword in which the transcribed bytes are NULL. It is empty
+// for level=0 subwords.
+func (p pipeline) settleLetters(origins [][]int, dummy string) {
+	if p.cov == nil {
+		return
+	}
+
+	for i, origin := range origins {
+		for _, j := range origin {
+			p.cov.derived[j] = true
+
+			if dummy != "" && dummy[i] == '\x00' {
+				p.cov.transcribed[j] = true
+			}
+		}
+	}
+}
+
+// collectResidue records the letters which have remained but never been
+// transcribed. A letter which a rewrite rule has removed is not residue.
+func (p pipeline) collectResidue() {
+	if p.cov == nil {
+		return
+	}
+
+	offsets := make([]int, 0, len(p.cov.input))
+	for i := range p.cov.input {
+		offsets = append(offsets, i)
+	}
+	sort.Ints(offsets)
+
+	for _, i := range offsets {
+		if p.cov.derived[i] && !p.cov.transcribed[i] {
+			p.cov.residue = append(p.cov.residue, p.cov.input[i])
+		}
+	}
+}
+
+// isLetterToTranscribe checks whether a character in a subword at the level
+// should be transcribed. Level=0 subwords also have punctuations, digits, and
+// Hangul. Level=1 subwords also have spaces and punctuations. Only the other
+// letters among them should be transcribed.
+func isLetterToTranscribe(ch rune, level int) bool {
+	if unicode.IsSpace(ch) || unicode.IsPunct(ch) {
+		return false
+	}
+	if level == 0 {
+		return unicode.IsLetter(ch) && !unicode.Is(unicode.Hangul, ch)
+	}
+	return true
+}
+
+// placehold replaces the untranscribed letters with the placeholder. Spaces
+// between them are collapsed into a single space.
+func (p pipeline) placehold(word string) []subword.Subword {
+	var subwords []subword.Subword
+	space, letter := false, false
+
+	for _, ch := range word {
+		if unicode.IsSpace(ch) {
+			if !space {
+				subwords = append(subwords, subword.New(" ", 1))
+			}
+			space, letter = true, false
+			continue
+		}
+
+		if !letter || !p.h.collapse {
+			// The placeholder is not a Jamo. Keep it as is.
+			subwords = append(subwords, subword.New(p.h.placeholder, 0))
+		}
+		space, letter = false, true
+	}
+
+	return subwords
+}
+
+// transcribeRules returns the transcribe rules including the fallback rules.
+// The fallback rules follow the primary rules. Their IDs are shifted to keep
+// the IDs unique.
+func (p pipeline) transcribeRules() []Rule {
+	rules := p.h.spec.Transcribe
+
+	if p.h.fallback == nil {
+		return rules
+	}
+
+	offset := len(rules)
+	fallback := p.h.fallback.Transcribe
+
+	all := make([]Rule, 0, len(rules)+len(fallback))
+	all = append(all, rules...)
+
+	for _, rule := range fallback {
+		rule.ID += offset
+		all = append(all, rule)
+	}
+
+	return all
+}
+
 // 6. Syllabify (Subwords -> Word)
 //
 // This step converts decomposed Jamo phonemes to composed Hangul syllables.
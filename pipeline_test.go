@@ -11,7 +11,7 @@ import (
 func TestTransliterate(t *testing.T) {
 	s := Spec{}
 	h := NewHangulizer(&s)
-	p := pipeline{h, nil}
+	p := pipeline{h: h}
 
 	s.script = scripts.Kana{}
 
@@ -26,7 +26,7 @@ func TestTransliterate(t *testing.T) {
 func TestTransliterateZWSP(t *testing.T) {
 	s := Spec{}
 	h := NewHangulizer(&s)
-	p := pipeline{h, nil}
+	p := pipeline{h: h}
 
 	assert.Equal(t, "foo", p.transliterate("f\u200Bo\u200Bo"))
 }
@@ -2,8 +2,14 @@ package hangulize
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
 
 	"github.com/hangulize/hre"
+	"github.com/hangulize/stringset"
 
 	"github.com/hangulize/hangulize/internal/subword"
 )
@@ -20,6 +26,8 @@ func (r Rule) String() string {
 }
 
 // Replace matches the word with the Pattern and replaces with the RPattern.
+// All matches are found in the original word before replacing. So a
+// replacement is never matched again by the same call.
 func (r Rule) Replace(word string) string {
 	rep := subword.NewReplacer(word, 0, 0)
 	repls := r.replacements(word)
@@ -27,6 +35,136 @@ func (r Rule) Replace(word string) string {
 	return rep.String()
 }
 
+// ReplaceNth replaces only the nth (1-based) match of the Pattern. If there's
+// no such match, the word is kept as is.
+func (r Rule) ReplaceNth(word string, nth int) string {
+	repls := r.replacements(word)
+
+	if nth < 1 || nth > len(repls) {
+		return word
+	}
+
+	rep := subword.NewReplacer(word, 0, 0)
+	rep.ReplaceBy(repls[nth-1])
+	return rep.String()
+}
+
+// ReplaceSeparated works like Replace but inserts the separator around each
+// replaced segment. The separator never appears at the word edges and only
+// once between adjoined segments:
+//
+//   "<abc>" -> "<abc>" with "." turns "abc" into "a.b.c".
+//
+func (r Rule) ReplaceSeparated(word, sep string) string {
+	rep := subword.NewReplacer(word, 0, 0)
+	repls := r.replacements(word)
+
+	for i, repl := range repls {
+		adjoined := i != 0 && repls[i-1].Stop == repl.Start
+
+		if repl.Start != 0 && !adjoined {
+			repl.Word = sep + repl.Word
+		}
+		if repl.Stop != len(word) {
+			repl.Word += sep
+		}
+
+		rep.ReplaceBy(repl)
+	}
+
+	return rep.String()
+}
+
+// ReplaceTransparent works like Replace but ignores the transparent characters
+// in the word while matching, as FindTransparent does. The transparent
+// characters outside of the matches are kept. The ones inside a match are
+// dropped with the match, unless keep is true. Then they follow the
+// replacement in order.
+func (r Rule) ReplaceTransparent(word, transparent string, keep bool) string {
+	filtered, matches, origins := findTransparent(r.From, word, transparent, -1)
+
+	var repls []subword.Replacement
+
+	for _, m := range matches {
+		repl, err := r.To.Interpolate(r.From, filtered, m)
+		if err != nil {
+			continue
+		}
+
+		start, stop := originSpan(origins, m[0], m[1])
+
+		if keep {
+			for _, ch := range word[start:stop] {
+				if strings.ContainsRune(transparent, ch) {
+					repl += string(ch)
+				}
+			}
+		}
+
+		repls = append(repls, subword.NewReplacement(start, stop, repl))
+	}
+
+	rep := subword.NewReplacer(word, 0, 0)
+	rep.ReplaceBy(repls...)
+	return rep.String()
+}
+
+// Debug replaces the input and compares the result with the expected word. It
+// returns the actual result and the rune position where the result diverges
+// from the expected. The position is -1 if they are the same.
+func (r Rule) Debug(input, expected string) (string, int) {
+	actual := r.Replace(input)
+
+	if actual == expected {
+		return actual, -1
+	}
+
+	a := []rune(actual)
+	e := []rune(expected)
+
+	pos := 0
+	for pos < len(a) && pos < len(e) && a[pos] == e[pos] {
+		pos++
+	}
+
+	return actual, pos
+}
+
+// IsIdempotent checks whether replacing twice equals replacing once for every
+// word in the corpus. If not, it returns the first word which makes the
+// difference. It means that the Rule feeds itself.
+func (r Rule) IsIdempotent(corpus []string) (bool, string) {
+	for _, word := range corpus {
+		once := r.Replace(word)
+
+		if r.Replace(once) != once {
+			return false, word
+		}
+	}
+	return true, ""
+}
+
+// ValidateTarget checks that the RPattern produces only the letters in the
+// target alphabet. It returns an error for each stray letter. "-" is always
+// allowed because it is the prefix of a tail Jaeum, such as "ㅎㅏ-ㄴ".
+func (r Rule) ValidateTarget(alphabet []string) []error {
+	var errs []error
+	target := stringset.NewStringSet(alphabet...)
+
+	for _, let := range r.To.Letters() {
+		if let == "-" {
+			continue
+		}
+
+		if !target.Has(let) {
+			err := errors.Errorf("%s produces %#v out of the target", r, let)
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
 // replacements indicates which ranges should be replaced.
 func (r Rule) replacements(word string) []subword.Replacement {
 	var repls []subword.Replacement
@@ -46,3 +184,134 @@ func (r Rule) replacements(word string) []subword.Replacement {
 
 	return repls
 }
+
+// -----------------------------------------------------------------------------
+
+// Conflict reports an override rule which has the same Pattern as a base
+// rule.
+type Conflict struct {
+	Base     Rule
+	Override Rule
+}
+
+// Duplicate checks whether both rules have the same RPattern too.
+func (c Conflict) Duplicate() bool {
+	return c.Base.To.String() == c.Override.To.String()
+}
+
+// MergeRules layers the override rules on the base rules.
+//
+// An override rule replaces the base rule which has the same Pattern in
+// place. It is reported as a Conflict. The other override rules follow the
+// base rules. The IDs of the merged rules are renumbered.
+//
+func MergeRules(base, override []Rule) ([]Rule, []Conflict) {
+	var conflicts []Conflict
+
+	merged := make([]Rule, len(base))
+	copy(merged, base)
+
+	index := make(map[string]int)
+	for i, rule := range base {
+		index[rule.From.String()] = i
+	}
+
+	for _, rule := range override {
+		i, ok := index[rule.From.String()]
+
+		if !ok {
+			index[rule.From.String()] = len(merged)
+			merged = append(merged, rule)
+			continue
+		}
+
+		conflicts = append(conflicts, Conflict{merged[i], rule})
+		merged[i] = rule
+	}
+
+	for i := range merged {
+		merged[i].ID = i
+	}
+
+	return merged, conflicts
+}
+
+// SortRules returns a copy of the rules ordered by the priority. A rule with
+// a higher priority applies earlier. The rules with the same priority keep
+// the document order. The IDs are not renumbered so that they still point to
+// the rules in the document.
+//
+// The result can be passed to ApplyStages or ApplyLimit:
+//
+//   priority := map[int]int{2: 1}
+//   sorted := SortRules(rules, func(r Rule) int { return priority[r.ID] })
+//
+func SortRules(rules []Rule, priority func(Rule) int) []Rule {
+	sorted := make([]Rule, len(rules))
+	copy(sorted, rules)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priority(sorted[i]) > priority(sorted[j])
+	})
+
+	return sorted
+}
+
+// CandidateSourcePatterns finds the rules which could have produced the given
+// output letter. It returns the indices of the rules whose RPattern contains
+// the letter.
+func CandidateSourcePatterns(rules []Rule, outputChar string) []int {
+	var indices []int
+
+	for i, rule := range rules {
+		for _, let := range rule.To.Letters() {
+			if let == outputChar {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+
+	return indices
+}
+
+// ApplyStages replaces the word by the rules in order like the Rewrite step.
+// It returns a snapshot of the word after each rule.
+func ApplyStages(rules []Rule, word string) []string {
+	stages := make([]string, len(rules))
+
+	for i, rule := range rules {
+		word = rule.Replace(word)
+		stages[i] = word
+	}
+
+	return stages
+}
+
+// ApplyLimit replaces the word by the rules in order like ApplyStages. But it
+// fails if the output grows more than maxRatio times the input in runes. It
+// guards against rules which explode the word on adversarial input.
+func ApplyLimit(rules []Rule, word string, maxRatio float64) (string, error) {
+	limit := expansionLimit(word, maxRatio)
+
+	for _, rule := range rules {
+		word = rule.Replace(word)
+
+		if utf8.RuneCountInString(word) > limit {
+			return "", errors.Errorf(
+				"%s expanded the word over %g times", rule, maxRatio)
+		}
+	}
+
+	return word, nil
+}
+
+// expansionLimit calculates the maximum runes which the word may grow to. An
+// empty word is regarded as 1 rune.
+func expansionLimit(word string, maxRatio float64) int {
+	n := utf8.RuneCountInString(word)
+	if n == 0 {
+		n = 1
+	}
+	return int(float64(n) * maxRatio)
+}
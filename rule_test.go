@@ -34,6 +34,92 @@ func TestRuleReplace(t *testing.T) {
 	assert.Equal(t, "abcbardef", r.Replace("abcfoodef"))
 }
 
+func TestRuleReplaceSelfMatchable(t *testing.T) {
+	p, _ := hre.NewPattern("a", nil, nil)
+	rp := hre.NewRPattern("aa", nil, nil)
+	r := Rule{0, p, rp}
+
+	assert.Equal(t, "aabaa", r.Replace("aba"))
+	assert.Equal(t, "aaaabaaaa", r.Replace(r.Replace("aba")))
+}
+
+func TestRuleReplaceNth(t *testing.T) {
+	p, _ := hre.NewPattern("foo", nil, nil)
+	rp := hre.NewRPattern("bar", nil, nil)
+	r := Rule{0, p, rp}
+
+	assert.Equal(t, "foo-bar-foo", r.ReplaceNth("foo-foo-foo", 2))
+	assert.Equal(t, "bar-foo-foo", r.ReplaceNth("foo-foo-foo", 1))
+
+	// Out of range.
+	assert.Equal(t, "foo-foo-foo", r.ReplaceNth("foo-foo-foo", 0))
+	assert.Equal(t, "foo-foo-foo", r.ReplaceNth("foo-foo-foo", 4))
+}
+
+func TestRuleReplaceSeparated(t *testing.T) {
+	vars := map[string][]string{"abc": {"a", "b", "c"}}
+	p, _ := hre.NewPattern("<abc>", nil, vars)
+	rp := hre.NewRPattern("<abc>", nil, vars)
+	r := Rule{0, p, rp}
+
+	assert.Equal(t, "a.b.c", r.ReplaceSeparated("abc", "."))
+	assert.Equal(t, "x.a.b.y", r.ReplaceSeparated("xaby", "."))
+	assert.Equal(t, "a.x.c", r.ReplaceSeparated("axc", "."))
+	assert.Equal(t, "xyz", r.ReplaceSeparated("xyz", "."))
+}
+
+func TestRuleDebug(t *testing.T) {
+	p, _ := hre.NewPattern("foo", nil, nil)
+	rp := hre.NewRPattern("bar", nil, nil)
+	r := Rule{0, p, rp}
+
+	actual, pos := r.Debug("abcfoodef", "abcbardef")
+	assert.Equal(t, "abcbardef", actual)
+	assert.Equal(t, -1, pos)
+
+	actual, pos = r.Debug("abcfoodef", "abcbazdef")
+	assert.Equal(t, "abcbardef", actual)
+	assert.Equal(t, 5, pos)
+
+	// Positions are in runes.
+	actual, pos = r.Debug("가foo", "가baz")
+	assert.Equal(t, "가bar", actual)
+	assert.Equal(t, 3, pos)
+
+	// One is a prefix of the other.
+	_, pos = r.Debug("abcfoodef", "abcbar")
+	assert.Equal(t, 6, pos)
+}
+
+func TestRuleIsIdempotent(t *testing.T) {
+	corpus := []string{"xyz", "foo", "foofoo"}
+
+	ok, word := newRule(0, "foo", "bar").IsIdempotent(corpus)
+	assert.True(t, ok)
+	assert.Equal(t, "", word)
+
+	// "o" -> "oo" feeds itself.
+	ok, word = newRule(0, "o", "oo").IsIdempotent(corpus)
+	assert.False(t, ok)
+	assert.Equal(t, "foo", word)
+}
+
+func TestRuleValidateTarget(t *testing.T) {
+	jamo := []string{"ㅂ", "ㅏ", "ㄹ"}
+
+	p, _ := hre.NewPattern("bar", nil, nil)
+	r := Rule{0, p, hre.NewRPattern("ㅂㅏㄹ", nil, nil)}
+	assert.Len(t, r.ValidateTarget(jamo), 0)
+
+	// "-" is a tail marker.
+	r = Rule{0, p, hre.NewRPattern("ㅂㅏ-ㄹ", nil, nil)}
+	assert.Len(t, r.ValidateTarget(jamo), 0)
+
+	// Stray Latin "r".
+	r = Rule{0, p, hre.NewRPattern("ㅂㅏr", nil, nil)}
+	assert.Len(t, r.ValidateTarget(jamo), 1)
+}
+
 func TestRuleUnmatchedVar(t *testing.T) {
 	vars := map[string][]string{
 		"foo": {"foo"},
@@ -47,3 +133,118 @@ func TestRuleUnmatchedVar(t *testing.T) {
 	// Silently, keep the original.
 	assert.Equal(t, "abcfoodef", r.Replace("abcfoodef"))
 }
+
+func TestMergeRules(t *testing.T) {
+	base := []Rule{
+		newRule(0, "a", "ㅏ"),
+		newRule(0, "b", "ㅂ"),
+		newRule(0, "c", "ㅋ"),
+	}
+	override := []Rule{
+		newRule(0, "c", "ㅊ"), // contradicts
+		newRule(0, "a", "ㅏ"), // duplicates
+		newRule(0, "d", "ㄷ"), // appended
+	}
+
+	merged, conflicts := MergeRules(base, override)
+
+	if assert.Len(t, merged, 4) {
+		assert.Equal(t, `"a" -> "ㅏ"`, merged[0].String())
+		assert.Equal(t, `"b" -> "ㅂ"`, merged[1].String())
+		assert.Equal(t, `"c" -> "ㅊ"`, merged[2].String())
+		assert.Equal(t, `"d" -> "ㄷ"`, merged[3].String())
+
+		for i, rule := range merged {
+			assert.Equal(t, i, rule.ID)
+		}
+	}
+
+	if assert.Len(t, conflicts, 2) {
+		assert.Equal(t, `"c" -> "ㅋ"`, conflicts[0].Base.String())
+		assert.False(t, conflicts[0].Duplicate())
+		assert.True(t, conflicts[1].Duplicate())
+	}
+
+	// The base rules are not modified.
+	assert.Equal(t, `"c" -> "ㅋ"`, base[2].String())
+}
+
+func TestSortRules(t *testing.T) {
+	rules := []Rule{
+		newRule(0, "a", "b"),
+		newRule(1, "b", "c"),
+		newRule(2, "x", "y"),
+	}
+
+	// Document order.
+	assert.Equal(t, []string{"b", "c", "c"}, ApplyStages(rules, "a"))
+
+	priority := map[int]int{1: 1}
+	sorted := SortRules(rules, func(r Rule) int { return priority[r.ID] })
+
+	assert.Equal(t, 1, sorted[0].ID)
+	assert.Equal(t, 0, sorted[1].ID)
+	assert.Equal(t, 2, sorted[2].ID)
+	assert.Equal(t, []string{"a", "b", "b"}, ApplyStages(sorted, "a"))
+
+	// The rules are not modified.
+	assert.Equal(t, 0, rules[0].ID)
+}
+
+func TestCandidateSourcePatterns(t *testing.T) {
+	rules := []Rule{
+		newRule(0, "b", "ㅂ"),
+		newRule(1, "p", "ㅍ"),
+		newRule(2, "bb", "ㅂㅂ"),
+		newRule(3, "v", "ㅂ"),
+	}
+
+	assert.Equal(t, []int{0, 2, 3}, CandidateSourcePatterns(rules, "ㅂ"))
+	assert.Equal(t, []int{1}, CandidateSourcePatterns(rules, "ㅍ"))
+	assert.Nil(t, CandidateSourcePatterns(rules, "ㄷ"))
+}
+
+func TestApplyStages(t *testing.T) {
+	rules := []Rule{
+		newRule(0, "ph", "f"),
+		newRule(1, "f", "ㅍ"),
+		newRule(2, "x", "ks"),
+	}
+
+	stages := ApplyStages(rules, "phox")
+	assert.Equal(t, []string{"fox", "ㅍox", "ㅍoks"}, stages)
+
+	assert.Equal(t, []string{}, ApplyStages(nil, "phox"))
+}
+
+func TestApplyLimit(t *testing.T) {
+	rules := []Rule{
+		newRule(0, "b", "ㅂ"),
+		newRule(1, "a", "ㅏ"),
+	}
+
+	word, err := ApplyLimit(rules, "ba", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "ㅂㅏ", word)
+
+	// Each consonant becomes three letters.
+	rules = append(rules, newRule(2, "ㅂ", "ㅂㅡㅂ"))
+
+	_, err = ApplyLimit(rules, "bbba", 2)
+	assert.Error(t, err)
+
+	word, err = ApplyLimit(rules, "bbba", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "ㅂㅡㅂㅂㅡㅂㅂㅡㅂㅏ", word)
+}
+
+func TestRuleReplaceTransparent(t *testing.T) {
+	r := newRule(0, "ab", "ㅂ")
+
+	// Soft hyphens between "a" and "b", and after "b".
+	word := "a\u00ADb\u00ADab"
+
+	assert.Equal(t, "a\u00ADb\u00ADㅂ", r.Replace(word))
+	assert.Equal(t, "ㅂ\u00ADㅂ", r.ReplaceTransparent(word, "\u00AD", false))
+	assert.Equal(t, "ㅂ\u00AD\u00ADㅂ", r.ReplaceTransparent(word, "\u00AD", true))
+}
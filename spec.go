@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -54,8 +55,61 @@ func (s Spec) GoString() string {
 	return fmt.Sprintf("hangulize.Spec{Lang.ID: %#v}", s.Lang.ID)
 }
 
-// ParseSpec parses a Spec from an HGL source.
+// Marshal returns the HGL source of the spec. UnmarshalSpec recompiles the
+// rules from it.
+//
+// It fails if the spec has no source or the rules have been modified after
+// parsing the source. Because the source wouldn't reproduce the rules.
+//
+func (s Spec) Marshal() ([]byte, error) {
+	if s.Source == "" {
+		return nil, errors.New("spec has no source")
+	}
+
+	parsed, err := UnmarshalSpec([]byte(s.Source))
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameRules(parsed.Rewrite, s.Rewrite) {
+		return nil, errors.New("rewrite rules differ from the source")
+	}
+	if !sameRules(parsed.Transcribe, s.Transcribe) {
+		return nil, errors.New("transcribe rules differ from the source")
+	}
+
+	return []byte(s.Source), nil
+}
+
+// UnmarshalSpec parses the HGL source which Spec.Marshal returned.
+func UnmarshalSpec(data []byte) (*Spec, error) {
+	return ParseSpec(bytes.NewReader(data))
+}
+
+// sameRules checks whether both rules have the same patterns in order.
+func sameRules(a, b []Rule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseSpec parses a Spec from an HGL source. A var value may have up to 256
+// runes.
 func ParseSpec(r io.Reader) (*Spec, error) {
+	return ParseSpecLimit(r, defaultMaxVarValueLength)
+}
+
+// ParseSpecLimit works like ParseSpec but limits the length of a var value to
+// maxVarValueLength runes instead of 256. Pass 0 to disable the limit.
+func ParseSpecLimit(r io.Reader, maxVarValueLength int) (*Spec, error) {
 	var err error
 	var sourceBuf bytes.Buffer
 
@@ -108,12 +162,20 @@ func ParseSpec(r io.Reader) (*Spec, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if err := checkMacros(macros); err != nil {
+			return nil, err
+		}
 	}
 
 	// vars
 	var vars map[string][]string
 	if sec, ok := h["vars"]; ok {
 		vars = sec.(*hgl.DictSection).Map()
+
+		if err := checkVars(vars, maxVarValueLength); err != nil {
+			return nil, err
+		}
 	}
 
 	// normalize
@@ -262,6 +324,107 @@ func newConfig(dict *hgl.DictSection) (*Config, error) {
 	return &config, nil
 }
 
+// -----------------------------------------------------------------------------
+// "macros" section
+
+// checkMacros checks that every macro has balanced lookaround braces. An
+// unbalanced brace would break the lookaround of every Pattern which uses
+// the macro.
+func checkMacros(macros map[string]string) error {
+	srcs := make([]string, 0, len(macros))
+	for src := range macros {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+
+	for _, src := range srcs {
+		depth := 0
+
+		for _, ch := range macros[src] {
+			switch ch {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+
+			if depth < 0 {
+				break
+			}
+		}
+
+		if depth != 0 {
+			return errors.Errorf(
+				"macro %#v has unbalanced lookaround braces: %#v",
+				src, macros[src])
+		}
+	}
+
+	return nil
+}
+
+// ResolveMacros picks an expansion of each macro for the language. Each macro
+// maps language tags to expansions. The expansion under the empty tag is the
+// default for the other languages. A macro without an expansion for the
+// language nor a default is left out so it stays a literal:
+//
+//   ResolveMacros(map[string]map[string]string{
+//     "@": {"": "<vowels>", "en": "<vowels>|y"},
+//   }, "en")
+//   // {"@": "<vowels>|y"}
+//
+// The result can be passed to hre.NewPattern as the macros.
+func ResolveMacros(macros map[string]map[string]string, lang string) map[string]string {
+	resolved := make(map[string]string, len(macros))
+
+	for src, exprs := range macros {
+		if expr, ok := exprs[lang]; ok {
+			resolved[src] = expr
+		} else if expr, ok := exprs[""]; ok {
+			resolved[src] = expr
+		}
+	}
+
+	return resolved
+}
+
+// -----------------------------------------------------------------------------
+// "vars" section
+
+// defaultMaxVarValueLength limits the length of a var value in runes. Every
+// var value becomes an alternative in the compiled regexps. So a
+// pathologically long value in a user-supplied spec would blow them up.
+// ParseSpecLimit overrides it.
+const defaultMaxVarValueLength = 256
+
+// checkVars checks that no var value is longer than max runes. 0 means
+// unlimited.
+func checkVars(vars map[string][]string, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, val := range vars[name] {
+			n := utf8.RuneCountInString(val)
+
+			if n > max {
+				return errors.Errorf(
+					"var %#v has a value longer than %d runes: %d runes",
+					name, max, n)
+			}
+		}
+	}
+
+	return nil
+}
+
 // -----------------------------------------------------------------------------
 // "rewrite"/"transcribe" section
 
@@ -276,17 +439,11 @@ func newRules(
 	rules := make([]Rule, len(pairs))
 
 	for i, pair := range pairs {
-		from, err := hre.NewPattern(pair.Left(), macros, vars)
+		from, err := newPattern(pair.Left(), macros, vars)
 		if err != nil {
 			return nil, err
 		}
 
-		negAWidth, negBWidth := from.NegativeLookaroundWidths()
-		if negAWidth == -1 || negBWidth == -1 {
-			return nil, errors.Errorf(
-				"%s contains unlimited negative lookaround", from)
-		}
-
 		right := pair.Right()
 		to := hre.NewRPattern(right[0], macros, vars)
 
@@ -296,6 +453,30 @@ func newRules(
 	return rules, nil
 }
 
+// newPattern compiles a Pattern for a rule. It rejects the Patterns which
+// Hangulize cannot use.
+func newPattern(
+	expr string,
+
+	macros map[string]string,
+	vars map[string][]string,
+
+) (*hre.Pattern, error) {
+
+	p, err := hre.NewPattern(expr, macros, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	negAWidth, negBWidth := p.NegativeLookaroundWidths()
+	if negAWidth == -1 || negBWidth == -1 {
+		return nil, errors.Errorf(
+			"%s contains unlimited negative lookaround", p)
+	}
+
+	return p, nil
+}
+
 // -----------------------------------------------------------------------------
 
 // collectPuncts collects punctuation characters from rewrite/transcribe rules.
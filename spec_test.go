@@ -2,6 +2,7 @@ package hangulize
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,3 +31,137 @@ func TestUnlimitedNegativeLookaround(t *testing.T) {
 	`))
 	assert.Error(t, err)
 }
+
+func TestEdgesOnlyPattern(t *testing.T) {
+	// Only ValidateSpec warns about it. The spec still loads.
+	for _, expr := range []string{"^^$$", "^$", "^^$", "^$$"} {
+		_, err := ParseSpec(bytes.NewBufferString(`
+			rewrite:
+				"` + expr + `" -> "foo"
+		`))
+		assert.NoError(t, err, expr)
+	}
+}
+
+func TestUnbalancedMacro(t *testing.T) {
+	for _, macro := range []string{"{<vowels>", "<vowels>}", "}<vowels>{"} {
+		_, err := ParseSpec(bytes.NewBufferString(`
+			macros:
+				"@" = "` + macro + `"
+		`))
+		if assert.Error(t, err, macro) {
+			assert.Contains(t, err.Error(), `"@"`)
+		}
+	}
+
+	_, err := ParseSpec(bytes.NewBufferString(`
+		macros:
+			"@" = "{<vowels>}"
+	`))
+	assert.NoError(t, err)
+}
+
+func TestResolveMacros(t *testing.T) {
+	macros := map[string]map[string]string{
+		"@": {"": "<vowels>", "en": "<vowels>|y", "de": "<vowels>|ä"},
+		"$": {"en": "<consonants>"},
+	}
+
+	en := ResolveMacros(macros, "en")
+	assert.Equal(t, map[string]string{"@": "<vowels>|y", "$": "<consonants>"}, en)
+
+	de := ResolveMacros(macros, "de")
+	assert.Equal(t, map[string]string{"@": "<vowels>|ä"}, de)
+
+	// Unspecified language falls back to the default.
+	it := ResolveMacros(macros, "it")
+	assert.Equal(t, map[string]string{"@": "<vowels>"}, it)
+}
+
+func TestLongVarValue(t *testing.T) {
+	long := strings.Repeat("a", 257)
+
+	_, err := ParseSpec(bytes.NewBufferString(`
+		vars:
+			"vowels" = "a", "` + long + `"
+	`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"vowels"`)
+	}
+
+	_, err = ParseSpec(bytes.NewBufferString(`
+		vars:
+			"vowels" = "a", "` + long[1:] + `"
+	`))
+	assert.NoError(t, err)
+}
+
+func TestParseSpecLimit(t *testing.T) {
+	_, err := ParseSpecLimit(bytes.NewBufferString(`
+		vars:
+			"vowels" = "a", "abcdefghi"
+	`), 8)
+	assert.Error(t, err)
+
+	_, err = ParseSpecLimit(bytes.NewBufferString(`
+		vars:
+			"vowels" = "a", "abcdefgh"
+	`), 8)
+	assert.NoError(t, err)
+
+	// No limit.
+	_, err = ParseSpecLimit(bytes.NewBufferString(`
+		vars:
+			"vowels" = "a", "`+strings.Repeat("a", 1000)+`"
+	`), 0)
+	assert.NoError(t, err)
+}
+
+func TestSpecMarshal(t *testing.T) {
+	spec := loadSpec("ita")
+
+	data, err := spec.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, spec.Source, string(data))
+
+	unmarshaled, err := UnmarshalSpec(data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, spec.Lang, unmarshaled.Lang)
+	assert.Len(t, unmarshaled.Rewrite, len(spec.Rewrite))
+	assert.Len(t, unmarshaled.Transcribe, len(spec.Transcribe))
+
+	for _, word := range []string{"Cappuccino", "allegretto", "Pinocchio"} {
+		assert.Equal(t, hangulize(spec, word), hangulize(unmarshaled, word))
+	}
+}
+
+func TestSpecMarshalWithoutSource(t *testing.T) {
+	var spec Spec
+	_, err := spec.Marshal()
+	assert.Error(t, err)
+}
+
+func TestSpecMarshalModified(t *testing.T) {
+	spec := mustParseSpec(`
+	transcribe:
+		"a" -> "ㅏ"
+	`)
+
+	_, err := spec.Marshal()
+	assert.NoError(t, err)
+
+	// The source doesn't have the rule.
+	spec.Transcribe = append(spec.Transcribe, newRule(1, "b", "ㅂ"))
+
+	_, err = spec.Marshal()
+	assert.Error(t, err)
+}
+
+func TestUnmarshalSpecError(t *testing.T) {
+	_, err := UnmarshalSpec([]byte(`
+		rewrite:
+			"{~.*}@_@" -> "o<-<"
+	`))
+	assert.Error(t, err)
+}
@@ -91,6 +91,31 @@ func (ts Traces) Render(w io.Writer) {
 	}
 }
 
+// RenderDOT generates a Graphviz DOT graph. The traced words become nodes and
+// each edge is labeled with the step and the rule which made the change.
+func (ts Traces) RenderDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph {")
+
+	for i, t := range ts {
+		fmt.Fprintf(w, "\t%d [label=%q];\n", i, t.Word)
+
+		if i == 0 {
+			continue
+		}
+
+		label := t.Step.String()
+		if t.HasRule {
+			label += " | " + t.Rule.String()
+		} else if t.Why != "" {
+			label += " | (" + t.Why + ")"
+		}
+
+		fmt.Fprintf(w, "\t%d -> %d [label=%q];\n", i-1, i, label)
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
 // -----------------------------------------------------------------------------
 
 // tracer collects tracing logs.
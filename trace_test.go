@@ -2,6 +2,7 @@ package hangulize
 
 import (
 	"bytes"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -39,3 +40,25 @@ func TestTracesRender(t *testing.T) {
 	assert.True(t, strings.Contains(rendered, "Cappuccino"))
 	assert.True(t, strings.Contains(rendered, "카푸치노"))
 }
+
+func TestTracesRenderDOT(t *testing.T) {
+	spec, _ := LoadSpec("ita")
+	h := NewHangulizer(spec)
+	_, traces := h.HangulizeTrace("Cappuccino")
+
+	var b bytes.Buffer
+	traces.RenderDOT(&b)
+	rendered := b.String()
+
+	assert.True(t, strings.HasPrefix(rendered, "digraph {\n"))
+	assert.True(t, strings.HasSuffix(rendered, "}\n"))
+
+	nodes := regexp.MustCompile(`(?m)^\t\d+ \[label=`)
+	edges := regexp.MustCompile(`(?m)^\t\d+ -> \d+ \[label=`)
+	assert.Len(t, nodes.FindAllString(rendered, -1), len(traces))
+	assert.Len(t, edges.FindAllString(rendered, -1), len(traces)-1)
+
+	assert.Contains(t, rendered, `0 [label="Cappuccino"];`)
+	assert.Contains(t, rendered, `0 -> 1 [label="Normalize | (latin)"];`)
+	assert.Contains(t, rendered, `"카푸치노"`)
+}
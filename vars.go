@@ -0,0 +1,208 @@
+package hangulize
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ShadowedVarValues reports the var values which are shadowed by an earlier
+// value in the same var.
+//
+// A var matches its values in the listed order. If an earlier value is a
+// prefix of a later one, such as "a" and "ab", the later one wins only when
+// the rest of the pattern rejects the earlier one.
+//
+func ShadowedVarValues(vars map[string][]string) map[string][]string {
+	shadowed := make(map[string][]string)
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := vars[name]
+
+		for i, val := range values {
+			for _, prev := range values[:i] {
+				if prev != "" && strings.HasPrefix(val, prev) {
+					shadowed[name] = append(shadowed[name], val)
+					break
+				}
+			}
+		}
+	}
+
+	return shadowed
+}
+
+// CaseVars returns a copy of the vars which includes the upper and lower case
+// variants of each value. It is useful for bicameral scripts such as Latin or
+// Greek so that the spec can list each value once:
+//
+//   "vowels" = "a", "e" -> "vowels" = "a", "A", "e", "E"
+//
+func CaseVars(vars map[string][]string) map[string][]string {
+	cased := make(map[string][]string, len(vars))
+
+	for name, values := range vars {
+		seen := make(map[string]bool)
+
+		for _, val := range values {
+			variants := []string{val, strings.ToUpper(val), strings.ToLower(val)}
+
+			for _, v := range variants {
+				if seen[v] {
+					continue
+				}
+				seen[v] = true
+				cased[name] = append(cased[name], v)
+			}
+		}
+	}
+
+	return cased
+}
+
+// CrossVars produces the cartesian product of two var values joined by the
+// separator. It is useful for syllable templates:
+//
+//   CrossVars([]string{"b", "p"}, []string{"a", "e"}, "")
+//   // ["ba", "be", "pa", "pe"]
+//
+func CrossVars(a, b []string, sep string) []string {
+	product := make([]string, 0, len(a)*len(b))
+
+	for _, x := range a {
+		for _, y := range b {
+			product = append(product, x+sep+y)
+		}
+	}
+
+	return product
+}
+
+// MapVars returns a copy of the vars with fn applied to every value. The
+// original vars are not modified.
+func MapVars(vars map[string][]string, fn func(string) string) map[string][]string {
+	mapped := make(map[string][]string, len(vars))
+
+	for name, values := range vars {
+		mappedValues := make([]string, len(values))
+
+		for i, val := range values {
+			mappedValues[i] = fn(val)
+		}
+
+		mapped[name] = mappedValues
+	}
+
+	return mapped
+}
+
+// maxVarRangeSize limits the number of letters in a var range. Every letter
+// becomes an alternative in the compiled regexps.
+const maxVarRangeSize = 256
+
+// ExpandVarRanges returns a copy of the vars whose range values, such as
+// "range:a-z", are expanded to every letter in the range. Other values, such
+// as "a-z", are kept as is. It fails if a range is malformed, reversed, or
+// has more than 256 letters.
+func ExpandVarRanges(vars map[string][]string) (map[string][]string, error) {
+	expanded := make(map[string][]string, len(vars))
+
+	for name, values := range vars {
+		for _, val := range values {
+			if !strings.HasPrefix(val, "range:") {
+				expanded[name] = append(expanded[name], val)
+				continue
+			}
+
+			chars := []rune(strings.TrimPrefix(val, "range:"))
+
+			if len(chars) != 3 || chars[1] != '-' {
+				return nil, errors.Errorf(
+					"var %s has malformed range: %#v", name, val)
+			}
+
+			from, to := chars[0], chars[2]
+			if from > to {
+				return nil, errors.Errorf(
+					"var %s has reversed range: %#v", name, val)
+			}
+
+			if to-from+1 > maxVarRangeSize {
+				return nil, errors.Errorf(
+					"var %s has range over %d letters: %#v",
+					name, maxVarRangeSize, val)
+			}
+
+			for ch := from; ch <= to; ch++ {
+				expanded[name] = append(expanded[name], string(ch))
+			}
+		}
+	}
+
+	return expanded, nil
+}
+
+// VarDiff reports the values which are only in a and only in b. The values
+// keep their order and appear once even if duplicated.
+func VarDiff(a, b []string) (onlyA, onlyB []string) {
+	return subtractValues(a, b), subtractValues(b, a)
+}
+
+// subtractValues returns the unique values in a which are not in b.
+func subtractValues(a, b []string) []string {
+	var diff []string
+
+	seen := make(map[string]bool)
+	for _, val := range b {
+		seen[val] = true
+	}
+
+	for _, val := range a {
+		if seen[val] {
+			continue
+		}
+		seen[val] = true
+		diff = append(diff, val)
+	}
+
+	return diff
+}
+
+// UntrimmedVarValues reports the var values which have leading or trailing
+// spaces. Such spaces are usually typos which make the values never match. A
+// value which consists of only spaces is intentional so it is not reported.
+func UntrimmedVarValues(vars map[string][]string) map[string][]string {
+	untrimmed := make(map[string][]string)
+
+	for name, values := range vars {
+		for _, val := range values {
+			if trimVarValue(val) != val {
+				untrimmed[name] = append(untrimmed[name], val)
+			}
+		}
+	}
+
+	return untrimmed
+}
+
+// TrimVars returns a copy of the vars with leading and trailing spaces
+// trimmed from each value. A value which consists of only spaces is kept.
+func TrimVars(vars map[string][]string) map[string][]string {
+	return MapVars(vars, trimVarValue)
+}
+
+// trimVarValue trims spaces from a var value unless it is only spaces.
+func trimVarValue(val string) string {
+	trimmed := strings.TrimSpace(val)
+	if trimmed == "" {
+		return val
+	}
+	return trimmed
+}
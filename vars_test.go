@@ -0,0 +1,140 @@
+package hangulize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hangulize/hre"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowedVarValues(t *testing.T) {
+	shadowed := ShadowedVarValues(map[string][]string{
+		"shadowed": {"a", "ab", "b", "bc"},
+		"ordered":  {"ab", "a", "bc", "b"},
+	})
+
+	assert.Equal(t, []string{"ab", "bc"}, shadowed["shadowed"])
+	assert.NotContains(t, shadowed, "ordered")
+}
+
+func TestCaseVars(t *testing.T) {
+	vars := map[string][]string{
+		"vowels": {"a", "e", "ε"},
+		"marks":  {"'", "A"},
+	}
+	cased := CaseVars(vars)
+
+	assert.Equal(t, []string{"a", "A", "e", "E", "ε", "Ε"}, cased["vowels"])
+	assert.Equal(t, []string{"'", "A", "a"}, cased["marks"])
+
+	// The original vars are not modified.
+	assert.Equal(t, []string{"a", "e", "ε"}, vars["vowels"])
+
+	p, _ := hre.NewPattern("<vowels>", nil, cased)
+	assert.Len(t, p.Find("A", -1), 1)
+	assert.Len(t, p.Find("Ε", -1), 1)
+}
+
+func TestCrossVars(t *testing.T) {
+	consonants := []string{"b", "p", "t"}
+	vowels := []string{"a", "e"}
+
+	assert.Equal(t, []string{
+		"ba", "be",
+		"pa", "pe",
+		"ta", "te",
+	}, CrossVars(consonants, vowels, ""))
+
+	assert.Equal(t, []string{"a-b", "a-p", "a-t"}, CrossVars(vowels[:1], consonants, "-"))
+	assert.Len(t, CrossVars(nil, vowels, ""), 0)
+}
+
+func TestMapVars(t *testing.T) {
+	vars := map[string][]string{
+		"vowels":     {"a*", "e", "i*"},
+		"consonants": {"b", "c*"},
+	}
+	trimStar := func(val string) string {
+		return strings.TrimSuffix(val, "*")
+	}
+
+	mapped := MapVars(vars, trimStar)
+
+	assert.Equal(t, []string{"a", "e", "i"}, mapped["vowels"])
+	assert.Equal(t, []string{"b", "c"}, mapped["consonants"])
+
+	// The original vars are not modified.
+	assert.Equal(t, []string{"a*", "e", "i*"}, vars["vowels"])
+	assert.Equal(t, []string{"b", "c*"}, vars["consonants"])
+}
+
+func TestExpandVarRanges(t *testing.T) {
+	vars, err := ExpandVarRanges(map[string][]string{
+		"lower":  {"range:a-z"},
+		"digits": {"range:0-3", "-", "x"},
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, vars["lower"], 26)
+	assert.Equal(t, []string{"0", "1", "2", "3", "-", "x"}, vars["digits"])
+
+	p, _ := hre.NewPattern("<lower>", nil, vars)
+	assert.Len(t, p.Find("q", -1), 1)
+	assert.Len(t, p.Find("Q", -1), 0)
+}
+
+func TestExpandVarRangesLiteral(t *testing.T) {
+	// Without "range:", "x-y" is just a literal.
+	vars, err := ExpandVarRanges(map[string][]string{"dash": {"x-y"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x-y"}, vars["dash"])
+
+	p, _ := hre.NewPattern("<dash>", nil, vars)
+	assert.Len(t, p.Find("x-y", -1), 1)
+	assert.Len(t, p.Find("x", -1), 0)
+}
+
+func TestExpandVarRangesReversed(t *testing.T) {
+	_, err := ExpandVarRanges(map[string][]string{"lower": {"range:z-a"}})
+	assert.Error(t, err)
+}
+
+func TestExpandVarRangesMalformed(t *testing.T) {
+	_, err := ExpandVarRanges(map[string][]string{"lower": {"range:az"}})
+	assert.Error(t, err)
+}
+
+func TestExpandVarRangesTooLarge(t *testing.T) {
+	// CJK Unified Ideographs
+	_, err := ExpandVarRanges(map[string][]string{"hanzi": {"range:一-鿿"}})
+	assert.Error(t, err)
+}
+
+func TestVarDiff(t *testing.T) {
+	a := []string{"a", "e", "i", "o", "u", "i"}
+	b := []string{"y", "u", "e", "w", "y"}
+
+	onlyA, onlyB := VarDiff(a, b)
+	assert.Equal(t, []string{"a", "i", "o"}, onlyA)
+	assert.Equal(t, []string{"y", "w"}, onlyB)
+
+	onlyA, onlyB = VarDiff(a, a)
+	assert.Len(t, onlyA, 0)
+	assert.Len(t, onlyB, 0)
+}
+
+func TestUntrimmedVarValues(t *testing.T) {
+	vars := map[string][]string{
+		"vowels": {"a", "e ", " i", "o"},
+		"spaces": {" ", "\t"},
+	}
+
+	untrimmed := UntrimmedVarValues(vars)
+	assert.Equal(t, map[string][]string{"vowels": {"e ", " i"}}, untrimmed)
+
+	trimmed := TrimVars(vars)
+	assert.Equal(t, []string{"a", "e", "i", "o"}, trimmed["vowels"])
+	assert.Equal(t, []string{" ", "\t"}, trimmed["spaces"])
+	assert.Len(t, UntrimmedVarValues(trimmed), 0)
+}